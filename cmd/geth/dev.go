@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
+)
+
+const (
+	devAccountPassword  = "dev"
+	devAccountBalance   = 1000000
+	devMiningDifficulty = 0
+)
+
+// setupDevDataDir creates a throwaway data dir with its own keystore holding a
+// dev account prefunded in the genesis. It returns the data dir and its keystore.
+func setupDevDataDir() (string, string, common.Address, error) {
+	dataDir, err := os.MkdirTemp("", "geth-dev-")
+	if err != nil {
+		return "", "", common.Address{}, err
+	}
+
+	keystoreDir := filepath.Join(dataDir, "keystore")
+
+	devAcc, err := wallet.NewKeystoreAccount(devAccountPassword, keystoreDir)
+	if err != nil {
+		return "", "", common.Address{}, err
+	}
+
+	genesis, err := json.Marshal(database.Genesis{
+		Balances: map[common.Address]uint{devAcc: devAccountBalance},
+		Symbol:   "ETH",
+	})
+	if err != nil {
+		return "", "", common.Address{}, err
+	}
+
+	err = database.InitDataDirIfNotExists(dataDir, genesis)
+	if err != nil {
+		return "", "", common.Address{}, err
+	}
+
+	fmt.Println("Running in dev mode:")
+	fmt.Printf("\t- data dir: %s\n", dataDir)
+	fmt.Printf("\t- keystore: %s\n", keystoreDir)
+	fmt.Printf("\t- dev account: %s (password '%s', balance %d)\n", devAcc.Hex(), devAccountPassword, devAccountBalance)
+
+	return dataDir, keystoreDir, devAcc, nil
+}
@@ -18,6 +18,23 @@ const (
 	flagBootstrapAcc  = "bootstrap-account"
 	flagBootstrapIp   = "bootstrap-ip"
 	flagBootstrapPort = "bootstrap-port"
+	flagNoMiner       = "no-internal-miner"
+	flagStratumPort   = "stratum-port"
+	flagShareDiff     = "stratum-share-difficulty"
+	flagSealerPwd     = "sealer-pwd"
+	flagDev           = "dev"
+	flagSigners       = "signers"
+	flagThreshold     = "threshold"
+	flagTxFile        = "tx"
+	flagAccount       = "account"
+	flagTo            = "to"
+	flagValue         = "value"
+	flagNonce         = "nonce"
+	flagFee           = "fee"
+	flagPoolGlobal    = "txpool-global-slots"
+	flagPoolAccount   = "txpool-account-slots"
+	flagPoolLifetime  = "txpool-lifetime"
+	flagPoolPriceBump = "txpool-price-bump"
 )
 
 func main() {
@@ -36,9 +53,8 @@ func main() {
 	}
 }
 
-func addDefaultRequiredFlags(cmd *cobra.Command) {
+func addDataDirFlag(cmd *cobra.Command) {
 	cmd.Flags().String(flagDataDir, "", "Absolute path to your node's data dir where the DB will be/is stored")
-	_ = cmd.MarkFlagRequired(flagDataDir)
 }
 
 func addKeystoreFlag(cmd *cobra.Command) {
@@ -46,9 +62,8 @@ func addKeystoreFlag(cmd *cobra.Command) {
 	_ = cmd.MarkFlagRequired(flagKeystoreFile)
 }
 
-func addMinerFlag(cmd *cobra.Command) {
+func addMinerAccountFlag(cmd *cobra.Command) {
 	cmd.Flags().String(flagMiner, "", "your node's miner account to receive the block rewards")
-	_ = cmd.MarkFlagRequired(flagMiner)
 }
 
 func addNodeHttpInfoFlags(cmd *cobra.Command) {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ngoduongkha/go-ethereum-cloner/database"
 	"github.com/ngoduongkha/go-ethereum-cloner/node"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
 	"github.com/spf13/cobra"
 )
 
@@ -21,6 +22,20 @@ func runCmd() *cobra.Command {
 			bootstrapIp, _ := cmd.Flags().GetString(flagBootstrapIp)
 			bootstrapPort, _ := cmd.Flags().GetUint64(flagBootstrapPort)
 			bootstrapAcc, _ := cmd.Flags().GetString(flagBootstrapAcc)
+			noMiner, _ := cmd.Flags().GetBool(flagNoMiner)
+			stratumPort, _ := cmd.Flags().GetUint64(flagStratumPort)
+			shareDifficulty, _ := cmd.Flags().GetUint(flagShareDiff)
+			sealerPwd, _ := cmd.Flags().GetString(flagSealerPwd)
+			dev, _ := cmd.Flags().GetBool(flagDev)
+			poolGlobal, _ := cmd.Flags().GetUint(flagPoolGlobal)
+			poolAccount, _ := cmd.Flags().GetUint(flagPoolAccount)
+			poolLifetime, _ := cmd.Flags().GetDuration(flagPoolLifetime)
+			poolPriceBump, _ := cmd.Flags().GetUint(flagPoolPriceBump)
+
+			if !dev && (!cmd.Flags().Changed(flagDataDir) || !cmd.Flags().Changed(flagMiner)) {
+				fmt.Printf("--%s and --%s are required unless running with --%s\n", flagDataDir, flagMiner, flagDev)
+				os.Exit(1)
+			}
 
 			fmt.Println("Launching Ethereum node and its HTTP API...")
 
@@ -32,7 +47,48 @@ func runCmd() *cobra.Command {
 				false,
 			)
 
-			n := node.New(getDataDirFromCmd(cmd), ip, port, database.NewAccount(miner), bootstrap, node.DefaultMiningDifficulty)
+			dataDir := getDataDirFromCmd(cmd)
+			minerAcc := database.NewAccount(miner)
+			miningDifficulty := uint(node.DefaultMiningDifficulty)
+			keystoreDir := wallet.GetKeystoreDirPath()
+
+			if dev {
+				devDataDir, devKeystoreDir, devAcc, err := setupDevDataDir()
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+
+				dataDir, keystoreDir, minerAcc, miningDifficulty = devDataDir, devKeystoreDir, devAcc, devMiningDifficulty
+				bootstrap = node.PeerNode{}
+			}
+
+			n := node.New(dataDir, ip, port, minerAcc, bootstrap, miningDifficulty)
+			n.SetKeystoreDir(keystoreDir)
+			if dev {
+				n.EnableInstantSeal()
+			}
+			if noMiner {
+				n.DisableInternalMiner()
+			}
+			n.SetTxPoolConfig(node.TxPoolConfig{
+				GlobalSlots:  poolGlobal,
+				AccountSlots: poolAccount,
+				Lifetime:     poolLifetime,
+				PriceBump:    poolPriceBump,
+			})
+			if stratumPort != 0 {
+				n.EnableStratum(stratumPort, shareDifficulty)
+			}
+			if sealerPwd != "" {
+				key, err := wallet.DecryptKeystoreAccount(minerAcc, sealerPwd, keystoreDir)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				n.SetSealerKey(key.PrivateKey)
+			}
+
 			err := n.Run(context.Background())
 			if err != nil {
 				fmt.Println(err)
@@ -41,10 +97,19 @@ func runCmd() *cobra.Command {
 		},
 	}
 
-	addDefaultRequiredFlags(runCmd)
+	addDataDirFlag(runCmd)
 	addNodeHttpInfoFlags(runCmd)
-	addMinerFlag(runCmd)
+	addMinerAccountFlag(runCmd)
 	addBootstrapInfoFlags(runCmd)
+	runCmd.Flags().Bool(flagNoMiner, false, "disable the built-in miner and only seal blocks submitted via the getwork/submitwork API")
+	runCmd.Flags().Uint64(flagStratumPort, 0, "TCP port of the built-in Stratum mining pool server (disabled when 0)")
+	runCmd.Flags().Uint(flagShareDiff, node.DefaultStratumShareDifficulty, "number of leading zero bytes a pool share must have")
+	runCmd.Flags().String(flagSealerPwd, "", "password of the miner's keystore account used to sign blocks under PoA consensus")
+	runCmd.Flags().Uint(flagPoolGlobal, node.DefaultTxPoolGlobalSlots, "max number of TXs in the txpool")
+	runCmd.Flags().Uint(flagPoolAccount, node.DefaultTxPoolAccountSlots, "max number of TXs of a single sender in the txpool")
+	runCmd.Flags().Duration(flagPoolLifetime, node.DefaultTxPoolLifetime, "how long a TX may wait in the txpool before it's evicted")
+	runCmd.Flags().Uint(flagPoolPriceBump, node.DefaultTxPoolPriceBump, "percentage a TX must raise the fee of a pooled TX with the same nonce by to replace it")
+	runCmd.Flags().Bool(flagDev, false, "run a throwaway dev node with a prefunded dev account, zero difficulty and a block sealed for every new TX")
 
 	return runCmd
 }
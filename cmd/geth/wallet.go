@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
 	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +25,10 @@ func walletCmd() *cobra.Command {
 
 	walletCmd.AddCommand(walletNewAccountCmd())
 	walletCmd.AddCommand(walletPrintPrivKeyCmd())
+	walletCmd.AddCommand(walletSignTxCmd())
+	walletCmd.AddCommand(walletMultisigAddressCmd())
+	walletCmd.AddCommand(walletMultisigTxCmd())
+	walletCmd.AddCommand(walletMultisigSignCmd())
 
 	return walletCmd
 }
@@ -33,7 +40,7 @@ func walletNewAccountCmd() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			password := getPassPhrase("Please enter a password to encrypt the new wallet:", true)
 
-			acc, err := wallet.NewKeystoreAccount(password)
+			acc, err := wallet.NewKeystoreAccount(password, wallet.GetKeystoreDirPath())
 			if err != nil {
 				fmt.Println(err)
 				os.Exit(1)
@@ -76,6 +83,193 @@ func walletPrintPrivKeyCmd() *cobra.Command {
 	return cmd
 }
 
+func walletSignTxCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sign-tx",
+		Short: "Builds a TX and signs it offline with a keystore account, printing the raw TX to submit to a node's /tx/send-raw.",
+		Run: func(cmd *cobra.Command, args []string) {
+			acc, _ := cmd.Flags().GetString(flagAccount)
+			to, _ := cmd.Flags().GetString(flagTo)
+			value, _ := cmd.Flags().GetUint(flagValue)
+			nonce, _ := cmd.Flags().GetUint(flagNonce)
+			fee, _ := cmd.Flags().GetUint(flagFee)
+
+			tx := database.NewTx(database.NewAccount(acc), database.NewAccount(to), value, nonce, "")
+			tx.Fee = fee
+
+			password := getPassPhrase("Please enter a password to decrypt the wallet:", false)
+
+			rawTx, err := wallet.SignRawTxWithKeystoreAccount(tx, tx.From, password, wallet.GetKeystoreDirPath())
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			fmt.Println(rawTx)
+		},
+	}
+
+	cmd.Flags().String(flagAccount, "", "sender account in the keystore")
+	cmd.Flags().String(flagTo, "", "recipient account")
+	cmd.Flags().Uint(flagValue, 0, "value to send")
+	cmd.Flags().Uint(flagNonce, 0, "next nonce of the sender account")
+	cmd.Flags().Uint(flagFee, 0, "TX fee, the flat TX fee when 0")
+	_ = cmd.MarkFlagRequired(flagAccount)
+	_ = cmd.MarkFlagRequired(flagTo)
+	_ = cmd.MarkFlagRequired(flagNonce)
+
+	return cmd
+}
+
+func walletMultisigAddressCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "multisig-address",
+		Short: "Prints the address of the M-of-N multisig account of the given signers.",
+		Run: func(cmd *cobra.Command, args []string) {
+			multisig, err := getMultisigFromCmd(cmd)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Multisig account: %s\n", multisig.Address().Hex())
+			fmt.Printf("Register it by sending a TX with 'register_multisig' set to: %s\n", toJson(multisig))
+		},
+	}
+
+	addMultisigFlags(cmd)
+
+	return cmd
+}
+
+func walletMultisigTxCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "multisig-tx",
+		Short: "Creates an unsigned TX from a multisig account in a file passed along to its signers.",
+		Run: func(cmd *cobra.Command, args []string) {
+			multisig, err := getMultisigFromCmd(cmd)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			to, _ := cmd.Flags().GetString(flagTo)
+			value, _ := cmd.Flags().GetUint(flagValue)
+			nonce, _ := cmd.Flags().GetUint(flagNonce)
+			fee, _ := cmd.Flags().GetUint(flagFee)
+			txFile, _ := cmd.Flags().GetString(flagTxFile)
+
+			tx := database.NewTx(multisig.Address(), database.NewAccount(to), value, nonce, "")
+			tx.Fee = fee
+
+			multisigTx, err := wallet.NewMultisigTx(tx, multisig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			err = writeTxFile(txFile, multisigTx)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Multisig TX saved in: %s\n", txFile)
+		},
+	}
+
+	addMultisigFlags(cmd)
+	cmd.Flags().String(flagTo, "", "recipient account")
+	cmd.Flags().Uint(flagValue, 0, "value to send")
+	cmd.Flags().Uint(flagNonce, 0, "next nonce of the multisig account")
+	cmd.Flags().Uint(flagFee, 0, "TX fee, the flat TX fee when 0")
+	cmd.Flags().String(flagTxFile, "", "path of the TX file to create")
+	_ = cmd.MarkFlagRequired(flagTo)
+	_ = cmd.MarkFlagRequired(flagNonce)
+	_ = cmd.MarkFlagRequired(flagTxFile)
+
+	return cmd
+}
+
+func walletMultisigSignCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "multisig-sign",
+		Short: "Unlocks a signer's keystore account and adds its signature to a multisig TX file.",
+		Run: func(cmd *cobra.Command, args []string) {
+			txFile, _ := cmd.Flags().GetString(flagTxFile)
+			acc, _ := cmd.Flags().GetString(flagAccount)
+
+			txJson, err := os.ReadFile(txFile)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			var tx database.SignedTx
+			err = json.Unmarshal(txJson, &tx)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			password := getPassPhrase("Please enter a password to decrypt the wallet:", false)
+
+			tx, err = wallet.SignMultisigTxWithKeystoreAccount(tx, database.NewAccount(acc), password, wallet.GetKeystoreDirPath())
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			err = writeTxFile(txFile, tx)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Signatures collected: %d of %d required\n", len(tx.Sigs), tx.Multisig.Threshold)
+		},
+	}
+
+	cmd.Flags().String(flagTxFile, "", "path of the multisig TX file to sign")
+	cmd.Flags().String(flagAccount, "", "signer account in the keystore")
+	_ = cmd.MarkFlagRequired(flagTxFile)
+	_ = cmd.MarkFlagRequired(flagAccount)
+
+	return cmd
+}
+
+func addMultisigFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice(flagSigners, nil, "comma separated signer accounts of the multisig account")
+	cmd.Flags().Uint(flagThreshold, 0, "number of signers required to authorize a TX")
+	_ = cmd.MarkFlagRequired(flagSigners)
+	_ = cmd.MarkFlagRequired(flagThreshold)
+}
+
+func getMultisigFromCmd(cmd *cobra.Command) (database.MultisigAccount, error) {
+	signers, _ := cmd.Flags().GetStringSlice(flagSigners)
+	threshold, _ := cmd.Flags().GetUint(flagThreshold)
+
+	accounts := make([]common.Address, len(signers))
+	for i, signer := range signers {
+		accounts[i] = database.NewAccount(signer)
+	}
+
+	return database.NewMultisigAccount(accounts, threshold)
+}
+
+func writeTxFile(path string, tx database.SignedTx) error {
+	return os.WriteFile(path, []byte(toJson(tx)), 0644)
+}
+
+func toJson(v interface{}) string {
+	vJson, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(vJson)
+}
+
 func getPassPhrase(prompt string, confirmation bool) string {
 	return utils.GetPassPhrase(prompt, confirmation)
 }
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const MaxBatchOutputs = 100
+
+// TxOutput is one of the payments of a batch TX.
+type TxOutput struct {
+	To    common.Address `json:"to"`
+	Value uint           `json:"value"`
+}
+
+// IsBatch reports whether the TX pays its Outputs instead of Value to To.
+func (t Tx) IsBatch() bool {
+	return len(t.Outputs) > 0
+}
+
+// TotalValue is the value the TX transfers to all its recipients.
+func (t Tx) TotalValue() uint {
+	total := t.Value
+	for _, out := range t.Outputs {
+		total += out.Value
+	}
+
+	return total
+}
+
+// validateBatch checks a batch TX up front, so its outputs are either all paid or none is.
+func validateBatch(tx SignedTx) error {
+	if !tx.IsBatch() {
+		return nil
+	}
+
+	if len(tx.Outputs) > MaxBatchOutputs {
+		return fmt.Errorf("wrong TX. Batch has %d outputs, more than the max %d", len(tx.Outputs), MaxBatchOutputs)
+	}
+
+	if tx.Value != 0 {
+		return fmt.Errorf("wrong TX. Batch TX must send its value in outputs, not 'value'")
+	}
+
+	total := uint(0)
+	for i, out := range tx.Outputs {
+		if out.Value > math.MaxUint-total {
+			return fmt.Errorf("wrong TX. Batch output %d overflows the TX value", i)
+		}
+		total += out.Value
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestTXsWithoutRecipientCreditNobody(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	miner := common.HexToAddress("0x22")
+	now := uint64(time.Now().Unix())
+
+	batch := NewTx(sender, common.Address{}, 0, 1, "")
+	batch.Outputs = []TxOutput{{common.HexToAddress("0x11"), 10}, {common.HexToAddress("0x12"), 20}}
+
+	register := NewTx(sender, common.Address{}, 0, 2, "")
+	register.Name = &NameOp{Type: NameOpRegister, Name: "alice"}
+
+	parent, err := s.AddBlock(mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, miner, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := NewBlock(parent, 1, 0, now+1, miner, []SignedTx{signTestTx(t, batch, key), signTestTx(t, register, key)})
+	_, err = s.AddBlock(mineTestBlock(t, block))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[common.HexToAddress("0x11")] != 10 || s.Balances[common.HexToAddress("0x12")] != 20 {
+		t.Fatal("expected the batch outputs to be paid")
+	}
+
+	if record, ok := s.NameRecord("alice"); !ok || record.Address != sender {
+		t.Fatalf("expected the name to resolve to the sender, got %+v", record)
+	}
+
+	if _, ok := s.Balances[common.Address{}]; ok {
+		t.Fatal("expected the empty address not to get a balance")
+	}
+
+	err = s.RemoveBlocks(storedTestBlock(t, s, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Balances[common.Address{}]; ok || s.Balances[sender] != 1000000 {
+		t.Fatalf("expected the TXs to be reverted without touching the empty address, sender balance is %d", s.Balances[sender])
+	}
+}
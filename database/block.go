@@ -39,23 +39,56 @@ type Block struct {
 }
 
 type BlockHeader struct {
-	Parent Hash           `json:"parent"`
-	Number uint64         `json:"number"`
-	Nonce  uint32         `json:"nonce"`
-	Time   uint64         `json:"time"`
-	Miner  common.Address `json:"miner"`
+	Parent  Hash           `json:"parent"`
+	Number  uint64         `json:"number"`
+	Nonce   uint32         `json:"nonce"`
+	Time    uint64         `json:"time"`
+	Miner   common.Address `json:"miner"`
+	BaseFee uint           `json:"base_fee,omitempty"`
+	Uncles  []BlockHeader  `json:"uncles,omitempty"`
+	Vote    *SealerVote    `json:"vote,omitempty"`
+	Seal    []byte         `json:"seal,omitempty"`
+	GasUsed uint           `json:"gas_used,omitempty"`
+
+	// PoA only: whether the block was sealed in turn, see PoA
+	Difficulty uint `json:"difficulty,omitempty"`
+
+	// Commits the header to the block's TXs, see Block.Hash
+	PayloadHash *Hash `json:"payload_hash,omitempty"`
 }
 
 type BlockFS struct {
 	Key   Hash  `json:"hash"`
 	Value Block `json:"block"`
+
+	// Receipts of the block's TXs, produced when it was applied
+	Receipts []Receipt `json:"receipts,omitempty"`
 }
 
 func NewBlock(parent Hash, number uint64, nonce uint32, time uint64, miner common.Address, txs []SignedTx) Block {
-	return Block{BlockHeader{parent, number, nonce, time, miner}, txs}
+	payloadHash, _ := PayloadHash(txs)
+
+	return Block{BlockHeader{Parent: parent, Number: number, Nonce: nonce, Time: time, Miner: miner, PayloadHash: &payloadHash}, txs}
+}
+
+// PayloadHash is the hash of the block's TXs its header commits to.
+func PayloadHash(txs []SignedTx) (Hash, error) {
+	txsJson, err := json.Marshal(txs)
+	if err != nil {
+		return Hash{}, err
+	}
+
+	return sha256.Sum256(txsJson), nil
 }
 
+// Hash of a block whose header commits to its payload is the hash of the header
+// alone, so the block can be verified from its header, e.g. when included as an
+// uncle. Blocks sealed before headers carried the payload hash hash the whole block.
 func (b Block) Hash() (Hash, error) {
+	if b.Header.PayloadHash != nil {
+		return b.Header.Hash()
+	}
+
 	blockJson, err := json.Marshal(b)
 	if err != nil {
 		return Hash{}, err
@@ -64,6 +97,54 @@ func (b Block) Hash() (Hash, error) {
 	return sha256.Sum256(blockJson), nil
 }
 
+func (h BlockHeader) Hash() (Hash, error) {
+	headerJson, err := json.Marshal(h)
+	if err != nil {
+		return Hash{}, err
+	}
+
+	return sha256.Sum256(headerJson), nil
+}
+
+// validatePayloadHash ensures the header commits to the block's actual TXs.
+func validatePayloadHash(b Block) error {
+	if b.Header.PayloadHash == nil {
+		return nil
+	}
+
+	payloadHash, err := PayloadHash(b.TXs)
+	if err != nil {
+		return err
+	}
+
+	if payloadHash != *b.Header.PayloadHash {
+		return fmt.Errorf("block payload hash must be '%x' not '%x'", payloadHash, *b.Header.PayloadHash)
+	}
+
+	return nil
+}
+
+// SealHash is the hash of the block without its PoA seal, signed by the sealer.
+func (b Block) SealHash() (Hash, error) {
+	b.Header.Seal = nil
+
+	return b.Hash()
+}
+
+// Signer recovers the account that sealed the block under PoA consensus.
+func (b Block) Signer() (common.Address, error) {
+	if len(b.Header.Seal) == 0 {
+		return common.Address{}, fmt.Errorf("block %d is not sealed", b.Header.Number)
+	}
+
+	sealHash, err := b.SealHash()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return recoverSigner(sealHash, b.Header.Seal)
+}
+
 func IsBlockHashValid(hash Hash, miningDifficulty uint) bool {
 	zeroesCount := uint(0)
 
@@ -79,3 +160,30 @@ func IsBlockHashValid(hash Hash, miningDifficulty uint) bool {
 
 	return zeroesCount == miningDifficulty
 }
+
+// BlockHashTarget returns the highest hash accepted by IsBlockHashValid for the given difficulty.
+// Staying below the target is not enough: IsBlockHashValid also requires the byte following the
+// miningDifficulty zero bytes to be non-zero, so hashes with more leading zero bytes are rejected.
+func BlockHashTarget(miningDifficulty uint) Hash {
+	target := Hash{}
+
+	for i := miningDifficulty; i < uint(len(target)); i++ {
+		target[i] = 0xff
+	}
+
+	return target
+}
+
+// LeadingZeroBytes counts the zero bytes the hash starts with.
+func LeadingZeroBytes(hash Hash) uint {
+	zeroesCount := uint(0)
+
+	for _, b := range hash {
+		if b != 0 {
+			break
+		}
+		zeroesCount++
+	}
+
+	return zeroesCount
+}
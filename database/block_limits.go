@@ -0,0 +1,102 @@
+package database
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	DefaultMaxBlockBytes = 1 << 20
+	DefaultMaxBlockTXs   = 1000
+
+	// Highest limits a genesis may set, the block DB readers are sized for them
+	MaxBlockBytesLimit = 4 << 20
+	MaxBlockTXsLimit   = 10000
+)
+
+const (
+	// Upper bounds of the JSON of a receipt without its balance deltas and logs, and of a log
+	maxReceiptBytes = 512
+	maxLogBytes     = 128
+
+	// maxBlockFSBytes bounds a block DB line: the block and its receipts, whose balance
+	// deltas take about as many bytes as the block's recipients and whose logs each cost GasLog
+	maxBlockFSBytes = 2*MaxBlockBytesLimit + MaxBlockTXsLimit*maxReceiptBytes + MaxBlockGas/GasLog*maxLogBytes
+)
+
+// newBlockDbScanner reads the block DB line by line. The default scanner would
+// stop at the first line longer than 64 KiB.
+func newBlockDbScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBlockFSBytes)
+
+	return scanner
+}
+
+// Size is the number of bytes the block takes when served to peers and stored in the DB.
+func (b Block) Size() (uint, error) {
+	blockJson, err := json.Marshal(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(len(blockJson)), nil
+}
+
+// TxSize is the number of bytes the TX adds to a block's payload, including the list separator.
+func TxSize(tx SignedTx) (uint, error) {
+	txJson, err := json.Marshal(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(len(txJson)) + 1, nil
+}
+
+func (s *State) MaxBlockBytes() uint {
+	return s.maxBlockBytes
+}
+
+func (s *State) MaxBlockTXs() uint {
+	return s.maxBlockTXs
+}
+
+func validateGenesisBlockLimits(gen Genesis) error {
+	if gen.MaxBlockBytes > MaxBlockBytesLimit {
+		return fmt.Errorf("genesis max block bytes %d is above the limit %d", gen.MaxBlockBytes, MaxBlockBytesLimit)
+	}
+
+	if gen.MaxBlockTXs > MaxBlockTXsLimit {
+		return fmt.Errorf("genesis max block TXs %d is above the limit %d", gen.MaxBlockTXs, MaxBlockTXsLimit)
+	}
+
+	return nil
+}
+
+func validateBlockLimits(b Block, s *State) error {
+	if uint(len(b.TXs)) > s.maxBlockTXs {
+		return fmt.Errorf("block has %d TXs, the limit is %d", len(b.TXs), s.maxBlockTXs)
+	}
+
+	gasLimit := uint(0)
+	for _, tx := range b.TXs {
+		gasLimit += tx.GasLimit
+	}
+
+	if gasLimit > MaxBlockGas {
+		return fmt.Errorf("block TXs gas limit is %d, the limit is %d", gasLimit, MaxBlockGas)
+	}
+
+	size, err := b.Size()
+	if err != nil {
+		return err
+	}
+
+	if size > s.maxBlockBytes {
+		return fmt.Errorf("block size is %d bytes, the limit is %d", size, s.maxBlockBytes)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestBlockLimits(t *testing.T) {
+	s, key := newTestStateWithGenesis(t, Genesis{MaxBlockTXs: 2, MaxBlockBytes: 2000})
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	recipient := common.HexToAddress("0x11")
+	now := uint64(time.Now().Unix())
+
+	txs := make([]SignedTx, 3)
+	for i := range txs {
+		txs[i] = signTestTx(t, NewTx(sender, recipient, 1, uint(i+1), ""), key)
+	}
+	large := signTestTx(t, NewTx(sender, recipient, 1, 2, strings.Repeat("a", 2000)), key)
+
+	tests := []struct {
+		name  string
+		txs   []SignedTx
+		valid bool
+	}{
+		{"too many TXs", txs, false},
+		{"too many bytes", []SignedTx{txs[0], large}, false},
+		{"within the limits", txs[:2], true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := s.AddBlock(mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, sender, test.txs)))
+			if test.valid && err != nil {
+				t.Fatalf("expected the block to be added. %s", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expected the block to be rejected")
+			}
+		})
+	}
+}
+
+func TestGenesisBlockLimitsAreBounded(t *testing.T) {
+	for _, genesis := range []string{
+		fmt.Sprintf(`{"symbol": "ETH", "max_block_txs": %d}`, MaxBlockTXsLimit+1),
+		fmt.Sprintf(`{"symbol": "ETH", "max_block_bytes": %d}`, MaxBlockBytesLimit+1),
+	} {
+		dataDir := t.TempDir()
+		err := InitDataDirIfNotExists(dataDir, []byte(genesis))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := NewStateFromDisk(dataDir, testMiningDifficulty)
+		if err == nil {
+			s.Close()
+			t.Fatalf("expected genesis %s to be rejected", genesis)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultMaxFutureBlockTime = 60
+	DefaultMedianTimeBlocks   = 11
+)
+
+// Clock returns the local time blocks are validated against. Replaceable for tests.
+type Clock func() time.Time
+
+// MedianTimePast returns the median timestamp of the latest blocks. A new block
+// must be newer than it, which stops a miner from dragging the chain time backwards.
+func (s *State) MedianTimePast() uint64 {
+	count := len(s.blockTimes)
+	if count == 0 {
+		return 0
+	}
+
+	window := int(s.medianTimeBlocks)
+	if window == 0 || window > count {
+		window = count
+	}
+
+	times := append([]uint64{}, s.blockTimes[count-window:]...)
+	sort.Slice(times, func(i, j int) bool {
+		return times[i] < times[j]
+	})
+
+	return times[len(times)/2]
+}
+
+func (s *State) SetClock(clock Clock) {
+	s.clock = clock
+}
+
+func validateBlockTime(b Block, s *State) error {
+	now := uint64(s.clock().Unix())
+	if b.Header.Time > now+s.maxFutureBlockTime {
+		return fmt.Errorf("block time %d is more than %ds ahead of local time %d", b.Header.Time, s.maxFutureBlockTime, now)
+	}
+
+	if len(s.blockTimes) == 0 {
+		return nil
+	}
+
+	medianTimePast := s.MedianTimePast()
+	if b.Header.Time <= medianTimePast {
+		return fmt.Errorf("block time %d must be greater than the median time %d of the last %d blocks", b.Header.Time, medianTimePast, s.medianTimeBlocks)
+	}
+
+	return nil
+}
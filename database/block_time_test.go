@@ -0,0 +1,52 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateBlockTime(t *testing.T) {
+	now := uint64(1700000000)
+	clock := func() time.Time {
+		return time.Unix(int64(now), 0)
+	}
+
+	// the median of the latest 3 blocks is now-20, the older block is outside the window
+	blockTimes := []uint64{now + 30, now - 30, now - 20, now - 10}
+
+	tests := []struct {
+		name       string
+		blockTimes []uint64
+		time       uint64
+		valid      bool
+	}{
+		{"at max future drift", nil, now + DefaultMaxFutureBlockTime, true},
+		{"over max future drift", nil, now + DefaultMaxFutureBlockTime + 1, false},
+		{"over max future drift above median time past", blockTimes, now + DefaultMaxFutureBlockTime + 1, false},
+		{"equal to median time past", blockTimes, now - 20, false},
+		{"below median time past", blockTimes, now - 25, false},
+		{"above median time past", blockTimes, now - 19, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &State{blockTimes: test.blockTimes, maxFutureBlockTime: DefaultMaxFutureBlockTime, medianTimeBlocks: 3}
+			s.SetClock(clock)
+
+			b := NewBlock(Hash{}, uint64(len(test.blockTimes)), 0, test.time, NewAccount("0x01"), nil)
+
+			err := validateBlockTime(b, s)
+			if test.valid && err != nil {
+				t.Fatalf("expected block time %d to be valid. %s", test.time, err)
+			}
+			if !test.valid && err == nil {
+				t.Fatalf("expected block time %d to be rejected", test.time)
+			}
+		})
+	}
+
+	s := &State{blockTimes: blockTimes, medianTimeBlocks: 3}
+	if mtp := s.MedianTimePast(); mtp != now-20 {
+		t.Fatalf("expected median time past %d, got %d", now-20, mtp)
+	}
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+)
+
+const DefaultMaxReorgDepth = 100
+
+// hardCodedCheckpoints pins the blocks of known networks. It is filled at release
+// time with the blocks of the networks the release joins, so development builds
+// ship none and only pin the genesis declared checkpoints, added on top of them.
+var hardCodedCheckpoints = map[uint64]Hash{}
+
+func (s *State) Checkpoints() map[uint64]Hash {
+	return s.checkpoints
+}
+
+// validateCheckpoint rejects a block conflicting with the checkpoint at its height.
+func validateCheckpoint(number uint64, hash Hash, s *State) error {
+	checkpoint, ok := s.checkpoints[number]
+	if ok && checkpoint != hash {
+		return fmt.Errorf("block %d hash '%x' conflicts with checkpoint '%x'", number, hash, checkpoint)
+	}
+
+	return nil
+}
+
+// ValidateCheckpoints checks a peer's chain against the checkpoints.
+func (s *State) ValidateCheckpoints(blocks []Block) error {
+	for _, b := range blocks {
+		hash, err := b.Hash()
+		if err != nil {
+			return err
+		}
+
+		err = validateCheckpoint(b.Header.Number, hash, s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateReorg refuses to roll the chain back to fromBlock when it would remove
+// more than the max reorg depth or a checkpointed block.
+func (s *State) ValidateReorg(fromBlock Block) error {
+	if !s.hasGenesisBlock {
+		return nil
+	}
+
+	latest := s.latestBlock.Header.Number
+
+	// an empty block means the whole chain, genesis block included, is removed
+	removeFrom := uint64(0)
+	if !reflect.DeepEqual(fromBlock, Block{}) {
+		removeFrom = fromBlock.Header.Number + 1
+	}
+
+	if removeFrom > latest {
+		return nil
+	}
+
+	depth := latest - removeFrom + 1
+	if depth > s.maxReorgDepth {
+		return fmt.Errorf("reorg of %d blocks exceeds the max reorg depth %d", depth, s.maxReorgDepth)
+	}
+
+	for number := range s.checkpoints {
+		if number >= removeFrom && number <= latest {
+			return fmt.Errorf("reorg from block %d would remove checkpointed block %d", removeFrom, number)
+		}
+	}
+
+	return nil
+}
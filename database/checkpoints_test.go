@@ -0,0 +1,73 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCheckpointsAndMaxReorgDepth(t *testing.T) {
+	miner := common.HexToAddress("0x22")
+	now := uint64(time.Now().Unix())
+
+	blocks := make([]Block, 4)
+	hashes := make([]Hash, len(blocks))
+	parent := Hash{}
+	for number := range blocks {
+		blocks[number] = mineTestBlock(t, NewBlock(parent, uint64(number), 0, now+uint64(number), miner, nil))
+
+		hash, err := blocks[number].Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[number], parent = hash, hash
+	}
+
+	s, _ := newTestStateWithGenesis(t, Genesis{MaxReorgDepth: 3, Checkpoints: map[uint64]Hash{1: hashes[1]}})
+
+	conflicting := mineTestBlock(t, NewBlock(hashes[0], 1, 0, now+1, common.HexToAddress("0x33"), nil))
+	if s.ValidateCheckpoints([]Block{blocks[0], conflicting}) == nil {
+		t.Fatal("expected a peer chain conflicting with the checkpoint to be rejected")
+	}
+
+	for number, block := range blocks {
+		_, err := s.AddBlock(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if number == 0 {
+			_, err = s.AddBlock(conflicting)
+			if err == nil {
+				t.Fatal("expected the block conflicting with the checkpoint to be rejected")
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		fromBlock Block
+		valid     bool
+	}{
+		{"deeper than the max reorg depth", Block{}, false},
+		{"removing the checkpointed block", storedTestBlock(t, s, 0), false},
+		{"within the max reorg depth", storedTestBlock(t, s, 1), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.RemoveBlocks(test.fromBlock)
+			if test.valid && err != nil {
+				t.Fatalf("expected the reorg to be allowed. %s", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expected the reorg to be refused")
+			}
+		})
+	}
+
+	if s.LatestBlockHash() != hashes[1] {
+		t.Fatal("expected only the allowed reorg to remove blocks")
+	}
+}
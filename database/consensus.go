@@ -0,0 +1,357 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	ConsensusPoW = "pow"
+	ConsensusPoA = "poa"
+)
+
+const (
+	// Difficulty of PoA blocks, making the fork choice prefer the chains sealed in turn
+	PoADifficultyInTurn    = 2
+	PoADifficultyOutOfTurn = 1
+
+	// Seconds after its parent before an out-of-turn sealer may seal a block,
+	// leaving the in-turn sealer the first chance
+	PoAOutOfTurnDelay = 15
+)
+
+// Consensus verifies how blocks are sealed and keeps any engine specific state
+// (e.g. the PoA sealer set) in sync with the applied blocks.
+type Consensus interface {
+	Name() string
+	// VerifySeal checks the block against the state it is about to be applied to.
+	VerifySeal(b Block, s *State) error
+	// Finalize updates the engine state once the block was applied.
+	Finalize(b Block) error
+	// Revert undoes Finalize when the block is removed from the chain.
+	Revert(b Block) error
+	Copy() Consensus
+}
+
+type ConsensusConfig struct {
+	Engine  string           `json:"engine"`
+	Sealers []common.Address `json:"sealers"`
+}
+
+func NewConsensus(cfg ConsensusConfig) (Consensus, error) {
+	switch cfg.Engine {
+	case "", ConsensusPoW:
+		return PoW{}, nil
+	case ConsensusPoA:
+		return NewPoA(cfg.Sealers)
+	}
+
+	return nil, fmt.Errorf("unknown consensus engine '%s'", cfg.Engine)
+}
+
+// PoW accepts blocks whose hash satisfies the state's mining difficulty.
+type PoW struct{}
+
+func (e PoW) Name() string {
+	return ConsensusPoW
+}
+
+func (e PoW) VerifySeal(b Block, s *State) error {
+	hash, err := b.Hash()
+	if err != nil {
+		return err
+	}
+
+	if !IsBlockHashValid(hash, s.miningDifficulty) {
+		return fmt.Errorf("invalid block hash %x", hash)
+	}
+
+	return nil
+}
+
+func (e PoW) Finalize(b Block) error {
+	return nil
+}
+
+func (e PoW) Revert(b Block) error {
+	return nil
+}
+
+func (e PoW) Copy() Consensus {
+	return e
+}
+
+type SealerVote struct {
+	Candidate common.Address `json:"candidate"`
+	Authorize bool           `json:"authorize"`
+}
+
+type poaSnapshot struct {
+	sealers []common.Address
+	votes   map[common.Address]map[common.Address]bool
+	recents map[uint64]common.Address
+}
+
+// PoA accepts blocks signed by the authorized sealers, Clique style. Sealers take
+// turns in round-robin, but when the in-turn sealer is offline any other sealer
+// may seal the block PoAOutOfTurnDelay seconds after its parent, with a lower
+// difficulty. A sealer may sign only one of any len(sealers)/2+1 consecutive
+// blocks, so a minority of sealers can't take over the chain.
+//
+// Sealers may vote in the header of the blocks they seal to authorize a new
+// sealer or to drop an existing one; a candidate is (de)authorized once more
+// than half of the sealers agree.
+type PoA struct {
+	sealers []common.Address
+	// candidate -> voting sealer -> authorize
+	votes map[common.Address]map[common.Address]bool
+	// block number -> sealer, of the latest blocks
+	recents map[uint64]common.Address
+	// engine state before each applied block, restored by Revert
+	history map[uint64]poaSnapshot
+}
+
+func NewPoA(sealers []common.Address) (*PoA, error) {
+	if len(sealers) == 0 {
+		return nil, fmt.Errorf("PoA consensus requires at least one sealer")
+	}
+
+	e := &PoA{
+		sealers: make([]common.Address, 0, len(sealers)),
+		votes:   make(map[common.Address]map[common.Address]bool),
+		recents: make(map[uint64]common.Address),
+		history: make(map[uint64]poaSnapshot),
+	}
+
+	for _, sealer := range sealers {
+		if !e.IsSealer(sealer) {
+			e.sealers = append(e.sealers, sealer)
+		}
+	}
+	e.sortSealers()
+
+	return e, nil
+}
+
+func (e *PoA) Name() string {
+	return ConsensusPoA
+}
+
+func (e *PoA) Sealers() []common.Address {
+	return append([]common.Address{}, e.sealers...)
+}
+
+func (e *PoA) IsSealer(acc common.Address) bool {
+	for _, sealer := range e.sealers {
+		if sealer == acc {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InTurnSealer returns the sealer expected to seal the block at the given height.
+func (e *PoA) InTurnSealer(number uint64) common.Address {
+	return e.sealers[number%uint64(len(e.sealers))]
+}
+
+// Difficulty returns the difficulty of the block at the given height sealed by the sealer.
+func (e *PoA) Difficulty(number uint64, sealer common.Address) uint {
+	if e.InTurnSealer(number) == sealer {
+		return PoADifficultyInTurn
+	}
+
+	return PoADifficultyOutOfTurn
+}
+
+// SignedRecently tells if the sealer signed one of the len(sealers)/2 blocks
+// before the given height, and so must not seal it.
+func (e *PoA) SignedRecently(sealer common.Address, number uint64) bool {
+	limit := uint64(len(e.sealers)/2 + 1)
+
+	for seen, recent := range e.recents {
+		if recent == sealer && number-seen < limit {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *PoA) VerifySeal(b Block, s *State) error {
+	signer, err := b.Signer()
+	if err != nil {
+		return err
+	}
+
+	if signer != b.Header.Miner {
+		return fmt.Errorf("block sealed by '%s' but miner is '%s'", signer.String(), b.Header.Miner.String())
+	}
+
+	if !e.IsSealer(signer) {
+		return fmt.Errorf("'%s' is not an authorized sealer", signer.String())
+	}
+
+	if e.SignedRecently(signer, b.Header.Number) {
+		return fmt.Errorf("'%s' signed one of the last %d blocks", signer.String(), len(e.sealers)/2)
+	}
+
+	difficulty := e.Difficulty(b.Header.Number, signer)
+	if b.Header.Difficulty != difficulty {
+		return fmt.Errorf("block %d sealed by '%s' must have difficulty %d not %d", b.Header.Number, signer.String(), difficulty, b.Header.Difficulty)
+	}
+
+	if difficulty == PoADifficultyOutOfTurn && s.hasGenesisBlock && b.Header.Time < s.latestBlock.Header.Time+PoAOutOfTurnDelay {
+		return fmt.Errorf("out-of-turn block %d must be sealed %ds after its parent", b.Header.Number, PoAOutOfTurnDelay)
+	}
+
+	vote := b.Header.Vote
+	if vote != nil && vote.Authorize == e.IsSealer(vote.Candidate) {
+		return fmt.Errorf("invalid vote for '%s'", vote.Candidate.String())
+	}
+
+	if vote != nil && !vote.Authorize && len(e.sealers) == 1 {
+		return fmt.Errorf("invalid vote to deauthorize '%s', the last sealer", vote.Candidate.String())
+	}
+
+	return nil
+}
+
+func (e *PoA) Finalize(b Block) error {
+	e.history[b.Header.Number] = e.snapshot()
+
+	e.recents[b.Header.Number] = b.Header.Miner
+	for seen := range e.recents {
+		if b.Header.Number-seen >= uint64(len(e.sealers)) {
+			delete(e.recents, seen)
+		}
+	}
+
+	vote := b.Header.Vote
+	if vote == nil {
+		return nil
+	}
+
+	if _, ok := e.votes[vote.Candidate]; !ok {
+		e.votes[vote.Candidate] = make(map[common.Address]bool)
+	}
+	e.votes[vote.Candidate][b.Header.Miner] = vote.Authorize
+
+	tally := 0
+	for _, authorize := range e.votes[vote.Candidate] {
+		if authorize == vote.Authorize {
+			tally++
+		}
+	}
+
+	if tally <= len(e.sealers)/2 {
+		return nil
+	}
+
+	delete(e.votes, vote.Candidate)
+
+	if vote.Authorize {
+		fmt.Printf("Sealer '%s' was authorized\n", vote.Candidate.String())
+		e.sealers = append(e.sealers, vote.Candidate)
+		e.sortSealers()
+
+		return nil
+	}
+
+	fmt.Printf("Sealer '%s' was deauthorized\n", vote.Candidate.String())
+	for i, sealer := range e.sealers {
+		if sealer == vote.Candidate {
+			e.sealers = append(e.sealers[:i], e.sealers[i+1:]...)
+			break
+		}
+	}
+
+	for _, voters := range e.votes {
+		delete(voters, vote.Candidate)
+	}
+
+	return nil
+}
+
+func (e *PoA) Revert(b Block) error {
+	snap, ok := e.history[b.Header.Number]
+	if !ok {
+		return fmt.Errorf("no PoA snapshot before block %d", b.Header.Number)
+	}
+
+	e.sealers = snap.sealers
+	e.votes = snap.votes
+	e.recents = snap.recents
+	delete(e.history, b.Header.Number)
+
+	return nil
+}
+
+// pruneConsensusHistory forgets the PoA snapshots of blocks too deep to ever be reorganized.
+func (s *State) pruneConsensusHistory(number uint64) {
+	poa, ok := s.consensus.(*PoA)
+	if ok && s.maxReorgDepth > 0 && number > s.maxReorgDepth {
+		delete(poa.history, number-s.maxReorgDepth-1)
+	}
+}
+
+// blockDifficulty weighs the block in the fork choice. PoW blocks don't carry a
+// difficulty and count as one, so the longest chain wins.
+func blockDifficulty(b Block) uint64 {
+	if b.Header.Difficulty == 0 {
+		return 1
+	}
+
+	return uint64(b.Header.Difficulty)
+}
+
+// TotalDifficulty is the summed difficulty of the chain's blocks, the heaviest chain winning a fork.
+func (s *State) TotalDifficulty() uint64 {
+	return s.totalDifficulty
+}
+
+func (e *PoA) Copy() Consensus {
+	c := &PoA{history: make(map[uint64]poaSnapshot)}
+
+	snap := e.snapshot()
+	c.sealers = snap.sealers
+	c.votes = snap.votes
+	c.recents = snap.recents
+
+	for number, snap := range e.history {
+		c.history[number] = snap
+	}
+
+	return c
+}
+
+func (e *PoA) snapshot() poaSnapshot {
+	snap := poaSnapshot{
+		sealers: append([]common.Address{}, e.sealers...),
+		votes:   make(map[common.Address]map[common.Address]bool),
+		recents: make(map[uint64]common.Address),
+	}
+
+	for number, sealer := range e.recents {
+		snap.recents[number] = sealer
+	}
+
+	for candidate, voters := range e.votes {
+		snap.votes[candidate] = make(map[common.Address]bool)
+		for voter, authorize := range voters {
+			snap.votes[candidate][voter] = authorize
+		}
+	}
+
+	return snap
+}
+
+func (e *PoA) sortSealers() {
+	sort.Slice(e.sealers, func(i, j int) bool {
+		return bytes.Compare(e.sealers[i][:], e.sealers[j][:]) < 0
+	})
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	MaxTxGas    = 1000000
+	MaxBlockGas = 10000000
+	// GasPrice is paid to the miner for each unit of gas a TX uses, on top of its fee
+	GasPrice = 1
+)
+
+type Contract struct {
+	Code    []byte            `json:"code"`
+	Storage map[uint64]uint64 `json:"storage"`
+}
+
+// contractChange records what a TX changed in the contracts so RemoveBlocks can undo it.
+type contractChange struct {
+	contract common.Address
+	deployed bool
+	key      uint64
+	prev     uint64
+	hadPrev  bool
+}
+
+// IsContractDeploy reports whether the TX deploys the hex code in its Data.
+// Deploys are sent to the empty address.
+func (t Tx) IsContractDeploy() bool {
+	return t.To == (common.Address{}) && strings.HasPrefix(t.Data, "0x") && !t.IsBatch()
+}
+
+// ContractAddress is derived from the deployer and its nonce, so each deploy gets a new address.
+func (t Tx) ContractAddress() common.Address {
+	data := append(t.From.Bytes(), binary.BigEndian.AppendUint64(nil, uint64(t.Nonce))...)
+
+	return common.BytesToAddress(crypto.Keccak256(data)[12:])
+}
+
+// GasFeeCap is the most the TX pays for the gas it uses.
+func (t Tx) GasFeeCap() uint {
+	return t.GasLimit * GasPrice
+}
+
+func (s *State) Contract(acc common.Address) (Contract, bool) {
+	c, ok := s.contracts[acc]
+
+	return c, ok
+}
+
+// GasUsed is the gas used by the contract TXs applied since the last block.
+func (s *State) GasUsed() uint {
+	return s.gasUsed
+}
+
+func decodeHexData(data string) ([]byte, error) {
+	if data == "" {
+		return []byte{}, nil
+	}
+
+	if !strings.HasPrefix(data, "0x") {
+		return nil, fmt.Errorf("contract data must be hex encoded with a '0x' prefix")
+	}
+
+	return hex.DecodeString(data[2:])
+}
+
+// validateContractTx checks the gas limit of TXs that deploy or call a contract
+// and that deploys don't overwrite an existing contract.
+func validateContractTx(tx SignedTx, s *State) error {
+	_, isCall := s.contracts[tx.To]
+	if !tx.IsContractDeploy() && !isCall {
+		return nil
+	}
+
+	if tx.GasLimit == 0 || tx.GasLimit > MaxTxGas {
+		return fmt.Errorf("wrong TX. Contract TX gas limit must be between 1 and %d, not %d", MaxTxGas, tx.GasLimit)
+	}
+
+	if tx.IsContractDeploy() {
+		if _, ok := s.contracts[tx.ContractAddress()]; ok {
+			return fmt.Errorf("wrong TX. Contract '%s' already exists", tx.ContractAddress().String())
+		}
+	}
+
+	return nil
+}
+
+// applyContractTx deploys or executes the TX's contract and returns the TX status.
+// A failing execution leaves the contract storage untouched but still uses its gas,
+// so the TX is included as failed instead of being dropped without paying for it.
+func applyContractTx(tx SignedTx, s *State) (uint, error) {
+	if tx.IsContractDeploy() {
+		code, err := decodeHexData(tx.Data)
+		if err != nil {
+			return 0, fmt.Errorf("wrong TX. %s", err.Error())
+		}
+
+		if len(code) > MaxCodeSize {
+			return 0, fmt.Errorf("wrong TX. Contract code is %d bytes, the limit is %d", len(code), MaxCodeSize)
+		}
+
+		gas := uint(len(code)) * GasCodeByte
+		if gas > tx.GasLimit {
+			return 0, fmt.Errorf("wrong TX. Deploying %d bytes of code needs %d gas, the limit is %d", len(code), gas, tx.GasLimit)
+		}
+
+		addr := tx.ContractAddress()
+		s.contracts[addr] = Contract{Code: code, Storage: make(map[uint64]uint64)}
+		s.recordContractChange(contractChange{contract: addr, deployed: true})
+		s.gasUsed += gas
+
+		return ReceiptStatusSuccess, nil
+	}
+
+	contract, ok := s.contracts[tx.To]
+	if !ok {
+		return ReceiptStatusSuccess, nil
+	}
+
+	callData, err := decodeHexData(tx.Data)
+	if err != nil {
+		return 0, fmt.Errorf("wrong TX. %s", err.Error())
+	}
+
+	ctx := ExecContext{Value: tx.Value, CallData: callData, Number: s.NextBlockNumber(), Time: s.nextTxTime()}
+	host := &txHost{contract: tx.To, storage: contract.Storage, writes: make(map[uint64]uint64)}
+
+	_, gas, err := Execute(contract.Code, ctx, host, tx.GasLimit)
+	s.gasUsed += gas
+	if err != nil {
+		txHash, _ := tx.Hash()
+		fmt.Printf("TX %s contract '%s' execution failed. %s\n", txHash.Hex(), tx.To.String(), err.Error())
+
+		return ReceiptStatusFailed, nil
+	}
+
+	for key, value := range host.writes {
+		prev, hadPrev := contract.Storage[key]
+		s.recordContractChange(contractChange{contract: tx.To, key: key, prev: prev, hadPrev: hadPrev})
+		contract.Storage[key] = value
+	}
+	s.txLogs = host.logs
+
+	return ReceiptStatusSuccess, nil
+}
+
+func (s *State) recordContractChange(change contractChange) {
+	number := s.NextBlockNumber()
+	s.contractJournal[number] = append(s.contractJournal[number], change)
+}
+
+// revertContracts undoes the contract changes of the block, latest first.
+func (s *State) revertContracts(number uint64) {
+	changes := s.contractJournal[number]
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+
+		if change.deployed {
+			delete(s.contracts, change.contract)
+			continue
+		}
+
+		if change.hadPrev {
+			s.contracts[change.contract].Storage[change.key] = change.prev
+		} else {
+			delete(s.contracts[change.contract].Storage, change.key)
+		}
+	}
+
+	delete(s.contractJournal, number)
+}
+
+// pruneContractJournal forgets the changes of blocks too deep to ever be reorganized.
+func (s *State) pruneContractJournal(number uint64) {
+	if s.maxReorgDepth > 0 && number > s.maxReorgDepth {
+		delete(s.contractJournal, number-s.maxReorgDepth-1)
+	}
+}
+
+func (s *State) copyContracts(c *State) {
+	c.contracts = make(map[common.Address]Contract)
+	for acc, contract := range s.contracts {
+		storage := make(map[uint64]uint64)
+		for key, value := range contract.Storage {
+			storage[key] = value
+		}
+		c.contracts[acc] = Contract{Code: contract.Code, Storage: storage}
+	}
+
+	c.contractJournal = make(map[uint64][]contractChange)
+	for number, changes := range s.contractJournal {
+		c.contractJournal[number] = append([]contractChange{}, changes...)
+	}
+}
+
+// txHost buffers the writes and logs of an execution, kept only once it succeeds.
+type txHost struct {
+	contract common.Address
+	storage  map[uint64]uint64
+	writes   map[uint64]uint64
+	logs     []Log
+}
+
+func (h *txHost) Load(key uint64) uint64 {
+	if value, ok := h.writes[key]; ok {
+		return value
+	}
+
+	return h.storage[key]
+}
+
+func (h *txHost) Store(key, value uint64) {
+	h.writes[key] = value
+}
+
+func (h *txHost) Log(topic, data uint64) {
+	h.logs = append(h.logs, Log{Address: h.contract, Topic: topic, Data: data})
+}
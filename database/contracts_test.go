@@ -0,0 +1,139 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestFailedContractCallPaysForGas(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	miner := common.HexToAddress("0x22")
+	now := uint64(time.Now().Unix())
+
+	// stores 7 at key 1, then reverts
+	code := "0x60076001556000fd"
+	deployGas, callGas := uint(8*GasCodeByte), uint(4*GasDefault+GasSStore)
+
+	deploy := NewTx(sender, common.Address{}, 0, 1, code)
+	deploy.GasLimit = deployGas
+	deployBlock := NewBlock(Hash{}, 0, 0, now, miner, []SignedTx{signTestTx(t, deploy, key)})
+	deployBlock.Header.GasUsed = deployGas
+
+	deployHash, err := s.AddBlock(mineTestBlock(t, deployBlock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance := s.Balances[sender]
+
+	call := NewTx(sender, deploy.ContractAddress(), 5, 2, "")
+	call.GasLimit = 1000
+	signedCall := signTestTx(t, call, key)
+	callBlock := NewBlock(deployHash, 1, 0, now+1, miner, []SignedTx{signedCall})
+	callBlock.Header.GasUsed = callGas
+
+	_, err = s.AddBlock(mineTestBlock(t, callBlock))
+	if err != nil {
+		t.Fatalf("expected the block with the failed call to be valid. %s", err)
+	}
+
+	callHash, err := signedCall.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, ok := s.Receipt(callHash)
+	if !ok || receipt.Status != ReceiptStatusFailed || receipt.GasUsed != callGas || receipt.Fee != TxFee+callGas*GasPrice {
+		t.Fatalf("expected a failed receipt using %d gas, got %+v", callGas, receipt)
+	}
+
+	if s.Balances[sender] != balance-TxFee-callGas*GasPrice || s.Account2Nonce[sender] != 2 {
+		t.Fatalf("expected the sender to pay the fee and gas only, balance is %d and nonce %d", s.Balances[sender], s.Account2Nonce[sender])
+	}
+
+	if s.Balances[call.To] != 0 || len(s.contracts[call.To].Storage) != 0 {
+		t.Fatal("expected the failed call to move no value and store nothing")
+	}
+
+	if s.Balances[miner] != 2*BlockReward+2*TxFee+(deployGas+callGas)*GasPrice {
+		t.Fatalf("expected the miner to be paid the fees and gas, balance is %d", s.Balances[miner])
+	}
+
+	err = s.RemoveBlocks(storedTestBlock(t, s, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[sender] != balance || s.Account2Nonce[sender] != 1 || s.Balances[miner] != BlockReward+TxFee+deployGas*GasPrice {
+		t.Fatalf("expected the failed call to be reverted, sender balance is %d and miner balance %d", s.Balances[sender], s.Balances[miner])
+	}
+
+	if _, ok := s.Receipt(callHash); ok {
+		t.Fatal("expected the failed call receipt to be removed")
+	}
+}
+
+func storedTestBlock(t *testing.T, s *State, height uint64) Block {
+	t.Helper()
+
+	block, err := GetBlockByHeightOrHashByFileName(s, height, "", s.dbFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return block.Value
+}
+
+func TestDeployCreditsValueToContract(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	now := uint64(time.Now().Unix())
+
+	deploy := NewTx(sender, common.Address{}, 25, 1, "0x60076001556000fd")
+	deploy.GasLimit = 8 * GasCodeByte
+	signedDeploy := signTestTx(t, deploy, key)
+
+	parent, err := s.AddBlock(mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, common.HexToAddress("0x22"), nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := NewBlock(parent, 1, 0, now+1, sender, []SignedTx{signedDeploy})
+	block.Header.GasUsed = deploy.GasLimit
+
+	_, err = s.AddBlock(mineTestBlock(t, block))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contract := deploy.ContractAddress()
+	if s.Balances[contract] != 25 {
+		t.Fatalf("expected the contract to be credited the deploy value, its balance is %d", s.Balances[contract])
+	}
+
+	if _, ok := s.Balances[common.Address{}]; ok {
+		t.Fatal("expected the empty address not to be credited")
+	}
+
+	deployHash, err := signedDeploy.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, ok := s.Receipt(deployHash)
+	if !ok || receipt.BalanceDeltas[contract] != 25 {
+		t.Fatalf("expected the receipt to record the contract credit, got %+v", receipt)
+	}
+
+	err = s.RemoveBlocks(storedTestBlock(t, s, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[contract] != 0 || s.Balances[sender] != 1000000 {
+		t.Fatalf("expected the deploy value to be reverted, balances are %d and %d", s.Balances[contract], s.Balances[sender])
+	}
+}
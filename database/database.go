@@ -1,7 +1,6 @@
 package database
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -21,7 +20,7 @@ func GetBlocksAfter(blockHash Hash, dataDir string) ([]Block, error) {
 		shouldStartCollecting = true
 	}
 
-	scanner := bufio.NewScanner(f)
+	scanner := newBlockDbScanner(f)
 	for scanner.Scan() {
 		if err := scanner.Err(); err != nil {
 			return nil, err
@@ -43,7 +42,7 @@ func GetBlocksAfter(blockHash Hash, dataDir string) ([]Block, error) {
 		}
 	}
 
-	return blocks, nil
+	return blocks, scanner.Err()
 }
 
 // GetBlockByHeightOrHash returns the requested block by hash or height.
@@ -78,7 +77,7 @@ func GetBlockByHeightOrHash(state *State, height uint64, hash, dataDir string) (
 	if err != nil {
 		return block, err
 	}
-	scanner := bufio.NewScanner(f)
+	scanner := newBlockDbScanner(f)
 	if scanner.Scan() {
 		if err := scanner.Err(); err != nil {
 			return block, err
@@ -89,7 +88,7 @@ func GetBlockByHeightOrHash(state *State, height uint64, hash, dataDir string) (
 		}
 	}
 
-	return block, nil
+	return block, scanner.Err()
 }
 
 func GetBlockByHeightOrHashByFileName(state *State, height uint64, hash, filename string) (BlockFS, error) {
@@ -122,7 +121,7 @@ func GetBlockByHeightOrHashByFileName(state *State, height uint64, hash, filenam
 	if err != nil {
 		return block, err
 	}
-	scanner := bufio.NewScanner(f)
+	scanner := newBlockDbScanner(f)
 	if scanner.Scan() {
 		if err := scanner.Err(); err != nil {
 			return block, err
@@ -133,5 +132,5 @@ func GetBlockByHeightOrHashByFileName(state *State, height uint64, hash, filenam
 		}
 	}
 
-	return block, nil
+	return block, scanner.Err()
 }
@@ -0,0 +1,77 @@
+package database
+
+const (
+	DefaultBaseFeeElasticity        = 2
+	DefaultBaseFeeChangeDenominator = 8
+)
+
+// FeeMarketConfig enables a per block base fee that is burned. The base fee moves
+// by up to 1/ChangeDenominator per block depending on how full the parent block
+// was compared to the target size, i.e. the max block bytes / Elasticity.
+type FeeMarketConfig struct {
+	InitialBaseFee    uint `json:"initial_base_fee"`
+	MinBaseFee        uint `json:"min_base_fee"`
+	Elasticity        uint `json:"elasticity"`
+	ChangeDenominator uint `json:"change_denominator"`
+}
+
+func (s *State) NextBaseFee() uint {
+	return s.nextBaseFee
+}
+
+// calcNextBaseFee returns the base fee of the block following the given parent.
+func (s *State) calcNextBaseFee(parent Block) (uint, error) {
+	if s.feeMarket == nil {
+		return 0, nil
+	}
+
+	used, err := parent.Size()
+	if err != nil {
+		return 0, err
+	}
+
+	baseFee := parent.Header.BaseFee
+	target := s.maxBlockBytes / s.feeMarket.Elasticity
+
+	switch {
+	case used > target:
+		delta := baseFee * (used - target) / target / s.feeMarket.ChangeDenominator
+		if delta == 0 {
+			delta = 1
+		}
+		baseFee += delta
+
+	case used < target:
+		delta := baseFee * (target - used) / target / s.feeMarket.ChangeDenominator
+		if delta > baseFee {
+			delta = baseFee
+		}
+		baseFee -= delta
+	}
+
+	if baseFee < s.feeMarket.MinBaseFee {
+		baseFee = s.feeMarket.MinBaseFee
+	}
+
+	return baseFee, nil
+}
+
+// blockTips sums the priority and gas fees the block's TXs pay to its miner.
+func blockTips(b Block) uint {
+	tips := b.Header.GasUsed * GasPrice
+	for _, tx := range b.TXs {
+		tips += tx.MinerTip(b.Header.BaseFee)
+	}
+
+	return tips
+}
+
+// blockFees sums the fees charged to the block's TXs senders, burned base and gas fees included.
+func blockFees(b Block) uint {
+	fees := b.Header.GasUsed * GasPrice
+	for _, tx := range b.TXs {
+		fees += tx.ChargedFee(b.Header.BaseFee)
+	}
+
+	return fees
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestBaseFeeIsBurnedAndFollowsBlockSize(t *testing.T) {
+	// blocks above 2000 bytes raise the base fee, smaller ones lower it
+	s, key := newTestStateWithGenesis(t, Genesis{
+		MaxBlockBytes: 4000,
+		FeeMarket:     &FeeMarketConfig{InitialBaseFee: 1000, MinBaseFee: 100},
+	})
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	miner, recipient := common.HexToAddress("0x22"), common.HexToAddress("0x11")
+	now := uint64(time.Now().Unix())
+
+	belowBaseFee := NewTx(sender, recipient, 10, 1, "")
+	belowBaseFee.MaxFee = 999
+	if ValidateTx(signTestTx(t, belowBaseFee, key), s) == nil {
+		t.Fatal("expected a TX whose max fee is below the base fee to be rejected")
+	}
+
+	tx := NewTx(sender, recipient, 10, 1, "")
+	tx.MaxFee, tx.PriorityFee = 1200, 30
+
+	block := NewBlock(Hash{}, 0, 0, now, miner, []SignedTx{signTestTx(t, tx, key)})
+	_, err := s.AddBlock(mineTestBlock(t, block))
+	if err == nil {
+		t.Fatal("expected a block without the base fee to be rejected")
+	}
+
+	block.Header.BaseFee = 1000
+	parent, err := s.AddBlock(mineTestBlock(t, block))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[sender] != 1000000-10-1000-30 || s.Balances[miner] != BlockReward+30 || s.LatestSupply().Burned != 1000 {
+		t.Fatalf("expected the base fee to be burned and the tip paid to the miner, balances are %d and %d, supply %+v", s.Balances[sender], s.Balances[miner], s.LatestSupply())
+	}
+
+	if s.NextBaseFee() >= 1000 {
+		t.Fatalf("expected the base fee to drop after a small block, it is %d", s.NextBaseFee())
+	}
+
+	large := NewTx(sender, recipient, 10, 2, strings.Repeat("a", 2500))
+	large.MaxFee = 1200
+
+	block = NewBlock(parent, 1, 0, now+1, miner, []SignedTx{signTestTx(t, large, key)})
+	block.Header.BaseFee = s.NextBaseFee()
+	_, err = s.AddBlock(mineTestBlock(t, block))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.NextBaseFee() <= block.Header.BaseFee {
+		t.Fatalf("expected the base fee to rise after a large block, it went from %d to %d", block.Header.BaseFee, s.NextBaseFee())
+	}
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	DefaultMinTxFee = TxFee
+	// MaxTxFee bounds the fees a TX may set, so fee sums can't overflow
+	MaxTxFee uint = math.MaxUint32
+)
+
+type FeeEstimate struct {
+	BaseFee uint `json:"base_fee"`
+	MinFee  uint `json:"min_fee"`
+	Low     uint `json:"low"`
+	Medium  uint `json:"medium"`
+	High    uint `json:"high"`
+	// number of TXs in the sampled blocks the estimate is based on
+	SampledTXs int `json:"sampled_txs"`
+}
+
+// validateTxCost rejects TXs with fees above MaxTxFee or whose cost overflows,
+// which would otherwise let the sender pay less than it sends.
+func validateTxCost(tx SignedTx) error {
+	if tx.Fee > MaxTxFee || tx.MaxFee > MaxTxFee || tx.PriorityFee > MaxTxFee {
+		return fmt.Errorf("wrong TX. Fees must not be above the max fee %d", MaxTxFee)
+	}
+
+	if tx.GasLimit > MaxTxGas {
+		return fmt.Errorf("wrong TX. Gas limit %d is above the max %d", tx.GasLimit, MaxTxGas)
+	}
+
+	if tx.TotalValue() > math.MaxUint-tx.FeeCap()-tx.NameFee()-tx.GasFeeCap() {
+		return fmt.Errorf("wrong TX. Value %d plus fees overflows the TX cost", tx.TotalValue())
+	}
+
+	return nil
+}
+
+func (s *State) MinTxFee() uint {
+	return s.minTxFee
+}
+
+// EstimateFee suggests fees out of the TXs included in the latest blocks. Low,
+// medium and high are the next base fee plus the 25th, 50th and 90th percentile
+// of the miner tips paid, and never go below the chain's minimum fee.
+func (s *State) EstimateFee(blocksCount uint64) (FeeEstimate, error) {
+	tips := make([]uint, 0)
+
+	if s.hasGenesisBlock {
+		latest := s.latestBlock.Header.Number
+		for height := latest; height+blocksCount > latest; height-- {
+			block, err := GetBlockByHeightOrHashByFileName(s, height, "", s.dbFile.Name())
+			if err != nil {
+				return FeeEstimate{}, err
+			}
+
+			for _, tx := range block.Value.TXs {
+				tips = append(tips, tx.MinerTip(block.Value.Header.BaseFee))
+			}
+
+			if height == 0 {
+				break
+			}
+		}
+	}
+
+	sort.Slice(tips, func(i, j int) bool {
+		return tips[i] < tips[j]
+	})
+
+	minFee := s.minTxFee
+	if s.nextBaseFee > minFee {
+		minFee = s.nextBaseFee
+	}
+
+	percentile := func(p int) uint {
+		if len(tips) == 0 || s.nextBaseFee+tips[len(tips)*p/100] < minFee {
+			return minFee
+		}
+
+		return s.nextBaseFee + tips[len(tips)*p/100]
+	}
+
+	return FeeEstimate{s.nextBaseFee, minFee, percentile(25), percentile(50), percentile(90), len(tips)}, nil
+}
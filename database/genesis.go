@@ -17,8 +17,31 @@ var genesisJson = `{
   }`
 
 type Genesis struct {
-	Balances map[common.Address]uint `json:"balances"`
-	Symbol   string                  `json:"symbol"`
+	Balances  map[common.Address]uint `json:"balances"`
+	Symbol    string                  `json:"symbol"`
+	Consensus ConsensusConfig         `json:"consensus"`
+
+	// Max seconds a block time may be ahead of the local clock and the number of
+	// blocks the median time past is computed from. Defaults apply when 0
+	MaxFutureBlockTime uint64 `json:"max_future_block_time"`
+	MedianTimeBlocks   uint   `json:"median_time_blocks"`
+
+	// Max size of a block and max number of TXs it may include. Defaults apply when 0
+	MaxBlockBytes uint `json:"max_block_bytes"`
+	MaxBlockTXs   uint `json:"max_block_txs"`
+
+	// Lowest fee a TX may pay. Defaults to TxFee when 0
+	MinTxFee uint `json:"min_tx_fee"`
+
+	// Burned base fee market. Disabled when not set
+	FeeMarket *FeeMarketConfig `json:"fee_market"`
+
+	// Max number of blocks a reorg may remove, and blocks (height -> hash) no reorg may remove
+	MaxReorgDepth uint64          `json:"max_reorg_depth"`
+	Checkpoints   map[uint64]Hash `json:"checkpoints"`
+
+	// Block reward schedule. Defaults to a flat BlockReward forever
+	Emission *EmissionConfig `json:"emission"`
 }
 
 func loadGenesis(path string) (Genesis, error) {
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	HTLCOpLock   = "lock"
+	HTLCOpClaim  = "claim"
+	HTLCOpRefund = "refund"
+)
+
+const (
+	HTLCStatusLocked   = "locked"
+	HTLCStatusClaimed  = "claimed"
+	HTLCStatusRefunded = "refunded"
+)
+
+// HTLCOp makes the TX a hash-time-locked contract step. A lock escrows the TX
+// Value for To under the HashLock until the Timeout, a block height or unix time
+// (see LockTimeThreshold). Before the timeout, a claim revealing the Preimage
+// pays the escrow to the recipient. After it, a refund returns it to the sender.
+// Claims and refunds reference the lock by its TX hash and are sent to whoever
+// receives the escrow.
+type HTLCOp struct {
+	Type     string `json:"type"`
+	HashLock Hash   `json:"hash_lock,omitempty"`
+	Timeout  uint64 `json:"timeout,omitempty"`
+	ID       Hash   `json:"id,omitempty"`
+	Preimage []byte `json:"preimage,omitempty"`
+}
+
+type HTLC struct {
+	ID        Hash           `json:"id"`
+	Sender    common.Address `json:"sender"`
+	Recipient common.Address `json:"recipient"`
+	Amount    uint           `json:"amount"`
+	HashLock  Hash           `json:"hash_lock"`
+	Timeout   uint64         `json:"timeout"`
+	Status    string         `json:"status"`
+}
+
+// IsHTLCLock reports whether the TX escrows its Value instead of paying it to To.
+func (t Tx) IsHTLCLock() bool {
+	return t.HTLC != nil && t.HTLC.Type == HTLCOpLock
+}
+
+func (s *State) HTLC(id Hash) (HTLC, bool) {
+	htlc, ok := s.htlcs[id]
+
+	return htlc, ok
+}
+
+func (h HTLC) isTimedOut(number, time uint64) bool {
+	return lockTimeValue(h.Timeout, number, time) > h.Timeout
+}
+
+func validateHTLCOp(tx SignedTx, s *State) error {
+	op := tx.HTLC
+	if op == nil {
+		return nil
+	}
+
+	if op.Type == HTLCOpLock {
+		if tx.Value == 0 {
+			return fmt.Errorf("wrong TX. HTLC lock must escrow a value")
+		}
+
+		if op.HashLock.IsEmpty() || op.Timeout == 0 {
+			return fmt.Errorf("wrong TX. HTLC lock requires a hash lock and a timeout")
+		}
+
+		return nil
+	}
+
+	if op.Type != HTLCOpClaim && op.Type != HTLCOpRefund {
+		return fmt.Errorf("wrong TX. Unknown HTLC operation '%s'", op.Type)
+	}
+
+	htlc, ok := s.htlcs[op.ID]
+	if !ok {
+		return fmt.Errorf("wrong TX. HTLC '%s' doesn't exist", op.ID.Hex())
+	}
+
+	if htlc.Status != HTLCStatusLocked {
+		return fmt.Errorf("wrong TX. HTLC '%s' is already %s", op.ID.Hex(), htlc.Status)
+	}
+
+	if tx.Value != 0 {
+		return fmt.Errorf("wrong TX. HTLC %s can't send a value", op.Type)
+	}
+
+	timedOut := htlc.isTimedOut(s.NextBlockNumber(), s.nextTxTime())
+
+	if op.Type == HTLCOpClaim {
+		if timedOut {
+			return fmt.Errorf("wrong TX. HTLC '%s' timed out at %d", op.ID.Hex(), htlc.Timeout)
+		}
+
+		if sha256.Sum256(op.Preimage) != htlc.HashLock {
+			return fmt.Errorf("wrong TX. Preimage doesn't match the HTLC '%s' hash lock", op.ID.Hex())
+		}
+
+		if tx.To != htlc.Recipient {
+			return fmt.Errorf("wrong TX. HTLC claim must be sent to the recipient '%s'", htlc.Recipient.String())
+		}
+
+		return nil
+	}
+
+	if !timedOut {
+		return fmt.Errorf("wrong TX. HTLC '%s' can't be refunded before %d", op.ID.Hex(), htlc.Timeout)
+	}
+
+	if tx.To != htlc.Sender {
+		return fmt.Errorf("wrong TX. HTLC refund must be sent to the sender '%s'", htlc.Sender.String())
+	}
+
+	return nil
+}
+
+func applyHTLCOp(tx SignedTx, s *State) error {
+	op := tx.HTLC
+	if op == nil {
+		return nil
+	}
+
+	switch op.Type {
+	case HTLCOpLock:
+		id, err := tx.Hash()
+		if err != nil {
+			return err
+		}
+
+		s.htlcs[id] = HTLC{ID: id, Sender: tx.From, Recipient: tx.To, Amount: tx.Value, HashLock: op.HashLock, Timeout: op.Timeout, Status: HTLCStatusLocked}
+
+	case HTLCOpClaim, HTLCOpRefund:
+		htlc := s.htlcs[op.ID]
+		s.Balances[tx.To] += htlc.Amount
+
+		htlc.Status = HTLCStatusClaimed
+		if op.Type == HTLCOpRefund {
+			htlc.Status = HTLCStatusRefunded
+		}
+		s.htlcs[op.ID] = htlc
+	}
+
+	return nil
+}
+
+// revertHTLCOp undoes applyHTLCOp. The block's TXs must be reverted latest first.
+func revertHTLCOp(tx SignedTx, s *State) error {
+	op := tx.HTLC
+	if op == nil {
+		return nil
+	}
+
+	switch op.Type {
+	case HTLCOpLock:
+		id, err := tx.Hash()
+		if err != nil {
+			return err
+		}
+
+		delete(s.htlcs, id)
+
+	case HTLCOpClaim, HTLCOpRefund:
+		htlc := s.htlcs[op.ID]
+		s.Balances[tx.To] -= htlc.Amount
+
+		htlc.Status = HTLCStatusLocked
+		s.htlcs[op.ID] = htlc
+	}
+
+	return nil
+}
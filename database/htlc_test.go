@@ -0,0 +1,98 @@
+package database
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestHTLCClaimAndRefund(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	miner := common.HexToAddress("0x22")
+	now := uint64(time.Now().Unix())
+
+	recipientKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient := crypto.PubkeyToAddress(recipientKey.PublicKey)
+
+	secret := []byte("secret")
+	hashLock := sha256.Sum256(secret)
+
+	htlcTx := func(from common.Address, to common.Address, value, nonce uint, op HTLCOp) Tx {
+		tx := NewTx(from, to, value, nonce, "")
+		tx.HTLC = &op
+
+		return tx
+	}
+
+	// the first HTLC times out after block 5, the second one after block 1
+	claimable := signTestTx(t, htlcTx(sender, recipient, 100, 2, HTLCOp{Type: HTLCOpLock, HashLock: hashLock, Timeout: 5}), key)
+	refundable := signTestTx(t, htlcTx(sender, recipient, 200, 3, HTLCOp{Type: HTLCOpLock, HashLock: hashLock, Timeout: 1}), key)
+	txs := []SignedTx{signTestTx(t, NewTx(sender, recipient, 1000, 1, ""), key), claimable, refundable}
+
+	parent, err := s.AddBlock(mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, miner, txs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimableID, err := claimable.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refundableID, err := refundable.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[sender] != 1000000-1300-3*TxFee || s.Balances[recipient] != 1000 {
+		t.Fatalf("expected the HTLC values to be escrowed, balances are %d and %d", s.Balances[sender], s.Balances[recipient])
+	}
+
+	invalid := []struct {
+		name string
+		tx   SignedTx
+	}{
+		{"claim with a wrong preimage", signTestTx(t, htlcTx(recipient, recipient, 0, 1, HTLCOp{Type: HTLCOpClaim, ID: claimableID, Preimage: []byte("guess")}), recipientKey)},
+		{"refund before the timeout", signTestTx(t, htlcTx(sender, sender, 0, 4, HTLCOp{Type: HTLCOpRefund, ID: claimableID}), key)},
+		{"unknown HTLC", signTestTx(t, htlcTx(recipient, recipient, 0, 1, HTLCOp{Type: HTLCOpClaim, ID: Hash{1}, Preimage: secret}), recipientKey)},
+	}
+
+	for _, test := range invalid {
+		t.Run(test.name, func(t *testing.T) {
+			if ValidateTx(test.tx, s) == nil {
+				t.Fatal("expected the HTLC op to be rejected")
+			}
+		})
+	}
+
+	claim := signTestTx(t, htlcTx(recipient, recipient, 0, 1, HTLCOp{Type: HTLCOpClaim, ID: claimableID, Preimage: secret}), recipientKey)
+	parent, err = s.AddBlock(mineTestBlock(t, NewBlock(parent, 1, 0, now+1, miner, []SignedTx{claim})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if htlc, _ := s.HTLC(claimableID); htlc.Status != HTLCStatusClaimed || s.Balances[recipient] != 1000-TxFee+100 {
+		t.Fatalf("expected the recipient to claim 100, HTLC is %s and balance %d", htlc.Status, s.Balances[recipient])
+	}
+
+	lateClaim := signTestTx(t, htlcTx(recipient, recipient, 0, 2, HTLCOp{Type: HTLCOpClaim, ID: refundableID, Preimage: secret}), recipientKey)
+	if ValidateTx(lateClaim, s) == nil {
+		t.Fatal("expected a claim after the timeout to be rejected")
+	}
+
+	refund := signTestTx(t, htlcTx(sender, sender, 0, 4, HTLCOp{Type: HTLCOpRefund, ID: refundableID}), key)
+	_, err = s.AddBlock(mineTestBlock(t, NewBlock(parent, 2, 0, now+2, miner, []SignedTx{refund})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if htlc, _ := s.HTLC(refundableID); htlc.Status != HTLCStatusRefunded || s.Balances[sender] != 1000000-1100-4*TxFee {
+		t.Fatalf("expected the sender to be refunded 200, HTLC is %s and balance %d", htlc.Status, s.Balances[sender])
+	}
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const MaxMultisigSigners = 16
+
+// MultisigAccount is an M-of-N account without a key of its own. TXs sent from
+// its address must carry the signatures of at least Threshold of its Signers.
+//
+// Signers are listed by address rather than by public key: every signature
+// recovers its signer's public key, so the address is all that's needed to check
+// it, the same way single signer TXs are checked against their From address.
+type MultisigAccount struct {
+	Signers   []common.Address `json:"signers"`
+	Threshold uint             `json:"threshold"`
+}
+
+// NewMultisigAccount sorts the signers so the same set always derives the same address.
+func NewMultisigAccount(signers []common.Address, threshold uint) (MultisigAccount, error) {
+	m := MultisigAccount{Signers: append([]common.Address{}, signers...), Threshold: threshold}
+	sort.Slice(m.Signers, func(i, j int) bool {
+		return bytes.Compare(m.Signers[i][:], m.Signers[j][:]) < 0
+	})
+
+	return m, m.Validate()
+}
+
+func (m MultisigAccount) Validate() error {
+	if len(m.Signers) == 0 || len(m.Signers) > MaxMultisigSigners {
+		return fmt.Errorf("multisig account must have between 1 and %d signers, not %d", MaxMultisigSigners, len(m.Signers))
+	}
+
+	if m.Threshold == 0 || m.Threshold > uint(len(m.Signers)) {
+		return fmt.Errorf("multisig threshold must be between 1 and %d, not %d", len(m.Signers), m.Threshold)
+	}
+
+	for i := 1; i < len(m.Signers); i++ {
+		if bytes.Compare(m.Signers[i-1][:], m.Signers[i][:]) >= 0 {
+			return fmt.Errorf("multisig signers must be sorted and unique")
+		}
+	}
+
+	return nil
+}
+
+// Address is derived from the signers and the threshold, like an account address from its public key.
+func (m MultisigAccount) Address() common.Address {
+	data := []byte("multisig")
+	for _, signer := range m.Signers {
+		data = append(data, signer[:]...)
+	}
+	data = binary.BigEndian.AppendUint64(data, uint64(m.Threshold))
+
+	return common.BytesToAddress(crypto.Keccak256(data)[12:])
+}
+
+func (m MultisigAccount) IsSigner(acc common.Address) bool {
+	for _, signer := range m.Signers {
+		if signer == acc {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countSigners returns how many distinct signers of the account signed the hash.
+// Signatures that don't recover a signer of the account don't count.
+func (m MultisigAccount) countSigners(hash Hash, sigs [][]byte) uint {
+	signed := make(map[common.Address]bool)
+	for _, sig := range sigs {
+		signer, err := recoverSigner(hash, sig)
+		if err != nil {
+			continue
+		}
+
+		if m.IsSigner(signer) {
+			signed[signer] = true
+		}
+	}
+
+	return uint(len(signed))
+}
+
+func (t SignedTx) IsMultisig() bool {
+	return t.Multisig != nil
+}
+
+func (t SignedTx) isMultisigAuthentic() (bool, error) {
+	err := t.Multisig.Validate()
+	if err != nil {
+		return false, err
+	}
+
+	if t.Multisig.Address() != t.From {
+		return false, nil
+	}
+
+	if len(t.Sigs) > len(t.Multisig.Signers) {
+		return false, fmt.Errorf("multisig TX carries %d signatures, more than the %d signers", len(t.Sigs), len(t.Multisig.Signers))
+	}
+
+	txHash, err := t.Tx.Hash()
+	if err != nil {
+		return false, err
+	}
+
+	return t.Multisig.countSigners(txHash, t.Sigs) >= t.Multisig.Threshold, nil
+}
+
+// validateMultisig checks TXs sent from a multisig account are from a registered
+// one and that registrations create a new account at the TX's recipient address.
+func validateMultisig(tx SignedTx, s *State) error {
+	if tx.IsMultisig() {
+		if _, ok := s.multisigs[tx.From]; !ok {
+			return fmt.Errorf("wrong TX. Multisig account '%s' is not registered", tx.From.String())
+		}
+	}
+
+	if tx.RegisterMultisig == nil {
+		return nil
+	}
+
+	err := tx.RegisterMultisig.Validate()
+	if err != nil {
+		return fmt.Errorf("wrong TX. %s", err.Error())
+	}
+
+	addr := tx.RegisterMultisig.Address()
+	if tx.To != addr {
+		return fmt.Errorf("wrong TX. Multisig registration must be sent to '%s', not '%s'", addr.String(), tx.To.String())
+	}
+
+	if _, ok := s.multisigs[addr]; ok {
+		return fmt.Errorf("wrong TX. Multisig account '%s' is already registered", addr.String())
+	}
+
+	return nil
+}
+
+func (s *State) Multisig(acc common.Address) (MultisigAccount, bool) {
+	m, ok := s.multisigs[acc]
+
+	return m, ok
+}
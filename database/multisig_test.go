@@ -0,0 +1,107 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestMultisigTX(t *testing.T) {
+	s, key := newTestState(t)
+	funder := crypto.PubkeyToAddress(key.PublicKey)
+	recipient := common.HexToAddress("0x11")
+	now := uint64(time.Now().Unix())
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	signers := make([]common.Address, 3)
+	for i := range keys {
+		k, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i], signers[i] = k, crypto.PubkeyToAddress(k.PublicKey)
+	}
+
+	m, err := NewMultisigAccount(signers, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	register := NewTx(funder, m.Address(), 1000, 1, "")
+	register.RegisterMultisig = &m
+	registerBlock := mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, funder, []SignedTx{signTestTx(t, register, key)}))
+	registerHash, err := s.AddBlock(registerBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spend := NewTx(m.Address(), recipient, 10, 1, "")
+	spendHash, err := spend.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sigs := make([][]byte, len(keys))
+	for i, k := range keys {
+		sigs[i], err = crypto.Sign(spendHash[:], k)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	junk := []byte{1, 2, 3}
+
+	tests := []struct {
+		name      string
+		sigs      [][]byte
+		authentic bool
+		err       bool
+	}{
+		{"threshold of signatures", [][]byte{sigs[0], sigs[1]}, true, false},
+		{"junk signature skipped", [][]byte{junk, sigs[2], sigs[0]}, true, false},
+		{"below threshold", [][]byte{sigs[0], junk}, false, false},
+		{"duplicate signature", [][]byte{sigs[1], sigs[1]}, false, false},
+		{"more signatures than signers", [][]byte{sigs[0], sigs[1], sigs[2], junk}, false, true},
+	}
+
+	var txHash Hash
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := SignedTx{Tx: spend, Multisig: &m, Sigs: test.sigs}
+
+			authentic, err := tx.IsAuthentic()
+			if authentic != test.authentic || (err != nil) != test.err {
+				t.Fatalf("expected authentic %t with error %t, got %t and %v", test.authentic, test.err, authentic, err)
+			}
+
+			hash, err := tx.Hash()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i > 0 && hash != txHash {
+				t.Fatal("expected the signatures not to change the TX hash")
+			}
+			txHash = hash
+		})
+	}
+
+	unsignedHash, err := SignedTx{Tx: spend}.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unsignedHash == txHash {
+		t.Fatal("expected the multisig account to be part of the TX hash")
+	}
+
+	signedSpend := SignedTx{Tx: spend, Multisig: &m, Sigs: [][]byte{sigs[2], junk, sigs[1]}}
+	_, err = s.AddBlock(mineTestBlock(t, NewBlock(registerHash, 1, 0, now+1, funder, []SignedTx{signedSpend})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[recipient] != 10 || s.Balances[m.Address()] != 1000-10-TxFee {
+		t.Fatalf("expected the multisig account to send 10, balances are %d and %d", s.Balances[recipient], s.Balances[m.Address()])
+	}
+}
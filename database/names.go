@@ -0,0 +1,198 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	NameOpRegister = "register"
+	NameOpTransfer = "transfer"
+	NameOpRenew    = "renew"
+)
+
+const (
+	// NameFee is burned by every registration and renewal
+	NameFee = 100
+	// NameRegistrationBlocks is how long a registration or renewal lasts
+	NameRegistrationBlocks = 100000
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]{2,31}$`)
+
+// NameOp registers a name resolving to the TX's To account, or to the sender
+// when To is empty. The owner can renew it before it expires, or transfer it to
+// To, which becomes both the new owner and the account the name resolves to.
+// Expired names can be registered again by anyone.
+type NameOp struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type NameRecord struct {
+	Name    string         `json:"name"`
+	Owner   common.Address `json:"owner"`
+	Address common.Address `json:"address"`
+	Expires uint64         `json:"expires"`
+}
+
+// nameChange is a name record before a block changed it, nil if it didn't exist.
+type nameChange struct {
+	name string
+	prev *NameRecord
+}
+
+// NameFee is the fee the TX burns on top of its TX fee.
+func (t Tx) NameFee() uint {
+	if t.Name == nil || t.Name.Type == NameOpTransfer {
+		return 0
+	}
+
+	return NameFee
+}
+
+func (r NameRecord) isExpired(number uint64) bool {
+	return number > r.Expires
+}
+
+// ResolveAccount accepts a hex address or an unexpired registered name. An empty
+// string resolves to the empty address.
+func (s *State) ResolveAccount(nameOrAddress string) (common.Address, error) {
+	if nameOrAddress == "" || common.IsHexAddress(nameOrAddress) {
+		return NewAccount(nameOrAddress), nil
+	}
+
+	record, ok := s.names[nameOrAddress]
+	if !ok || record.isExpired(s.NextBlockNumber()) {
+		return common.Address{}, fmt.Errorf("'%s' is neither an address nor a registered name", nameOrAddress)
+	}
+
+	return record.Address, nil
+}
+
+func (s *State) NameRecord(name string) (NameRecord, bool) {
+	record, ok := s.names[name]
+
+	return record, ok
+}
+
+func validateNameOp(tx SignedTx, s *State) error {
+	op := tx.Name
+	if op == nil {
+		return nil
+	}
+
+	record, exists := s.names[op.Name]
+	if exists && record.isExpired(s.NextBlockNumber()) {
+		exists = false
+	}
+
+	switch op.Type {
+	case NameOpRegister:
+		if !nameRegexp.MatchString(op.Name) {
+			return fmt.Errorf("wrong TX. Name '%s' must be 3 to 32 lowercase letters, digits or dashes, starting with a letter", op.Name)
+		}
+
+		if exists {
+			return fmt.Errorf("wrong TX. Name '%s' is already registered", op.Name)
+		}
+
+	case NameOpTransfer, NameOpRenew:
+		if !exists {
+			return fmt.Errorf("wrong TX. Name '%s' isn't registered", op.Name)
+		}
+
+		if record.Owner != tx.From {
+			return fmt.Errorf("wrong TX. Name '%s' is owned by '%s'", op.Name, record.Owner.String())
+		}
+
+		if op.Type == NameOpTransfer && tx.To == (common.Address{}) {
+			return fmt.Errorf("wrong TX. Name '%s' must be transferred to an account", op.Name)
+		}
+
+	default:
+		return fmt.Errorf("wrong TX. Unknown name operation '%s'", op.Type)
+	}
+
+	return nil
+}
+
+func applyNameOp(tx SignedTx, s *State) {
+	op := tx.Name
+	if op == nil {
+		return
+	}
+
+	number := s.NextBlockNumber()
+	change := nameChange{name: op.Name}
+	record, ok := s.names[op.Name]
+	if ok {
+		prev := record
+		change.prev = &prev
+	}
+	s.nameJournal[number] = append(s.nameJournal[number], change)
+
+	switch op.Type {
+	case NameOpRegister:
+		record = NameRecord{Name: op.Name, Owner: tx.From, Address: tx.To, Expires: number + NameRegistrationBlocks}
+		if tx.To == (common.Address{}) {
+			record.Address = tx.From
+		}
+
+	case NameOpTransfer:
+		record.Owner = tx.To
+		record.Address = tx.To
+
+	case NameOpRenew:
+		record.Expires += NameRegistrationBlocks
+	}
+
+	s.names[op.Name] = record
+}
+
+// revertNames restores the names the block changed, latest change first.
+func (s *State) revertNames(number uint64) {
+	changes := s.nameJournal[number]
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+
+		if change.prev == nil {
+			delete(s.names, change.name)
+		} else {
+			s.names[change.name] = *change.prev
+		}
+	}
+
+	delete(s.nameJournal, number)
+}
+
+// pruneNameJournal forgets the changes of blocks too deep to ever be reorganized.
+func (s *State) pruneNameJournal(number uint64) {
+	if s.maxReorgDepth > 0 && number > s.maxReorgDepth {
+		delete(s.nameJournal, number-s.maxReorgDepth-1)
+	}
+}
+
+func (s *State) copyNames(c *State) {
+	c.names = make(map[string]NameRecord)
+	for name, record := range s.names {
+		c.names[name] = record
+	}
+
+	c.nameJournal = make(map[uint64][]nameChange)
+	for number, changes := range s.nameJournal {
+		c.nameJournal[number] = append([]nameChange{}, changes...)
+	}
+}
+
+// blockNameFees sums the name fees burned by the block's TXs.
+func blockNameFees(b Block) uint {
+	fees := uint(0)
+	for _, tx := range b.TXs {
+		fees += tx.NameFee()
+	}
+
+	return fees
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestNameRegistry(t *testing.T) {
+	s, key := newTestState(t)
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	miner, newOwner := common.HexToAddress("0x22"), common.HexToAddress("0x11")
+	now := uint64(time.Now().Unix())
+
+	parent, err := s.AddBlock(mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, miner, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nameTx := func(to common.Address, nonce uint, op NameOp) SignedTx {
+		tx := NewTx(owner, to, 0, nonce, "")
+		tx.Name = &op
+
+		return signTestTx(t, tx, key)
+	}
+
+	txs := []SignedTx{
+		nameTx(common.Address{}, 1, NameOp{Type: NameOpRegister, Name: "alice"}),
+		nameTx(common.Address{}, 2, NameOp{Type: NameOpRenew, Name: "alice"}),
+	}
+	parent, err = s.AddBlock(mineTestBlock(t, NewBlock(parent, 1, 0, now+1, miner, txs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, _ := s.NameRecord("alice")
+	if record.Address != owner || record.Expires != 1+2*NameRegistrationBlocks || s.LatestSupply().Burned != 2*NameFee {
+		t.Fatalf("expected the renewed name to resolve to its owner and both name fees to be burned, got %+v and supply %+v", record, s.LatestSupply())
+	}
+
+	transfer := nameTx(newOwner, 3, NameOp{Type: NameOpTransfer, Name: "alice"})
+	_, err = s.AddBlock(mineTestBlock(t, NewBlock(parent, 2, 0, now+2, miner, []SignedTx{transfer})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := s.ResolveAccount("alice")
+	if err != nil || resolved != newOwner {
+		t.Fatalf("expected the name to resolve to the new owner, got %s. %v", resolved.String(), err)
+	}
+
+	tests := []struct {
+		name string
+		op   NameOp
+	}{
+		{"registered name", NameOp{Type: NameOpRegister, Name: "alice"}},
+		{"invalid name", NameOp{Type: NameOpRegister, Name: "Al"}},
+		{"renewal by the previous owner", NameOp{Type: NameOpRenew, Name: "alice"}},
+		{"unregistered name", NameOp{Type: NameOpTransfer, Name: "bob"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if ValidateTx(nameTx(newOwner, 4, test.op), s) == nil {
+				t.Fatal("expected the name op to be rejected")
+			}
+		})
+	}
+
+	err = s.RemoveBlocks(storedTestBlock(t, s, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ResolveAccount("alice"); err == nil {
+		t.Fatal("expected removing the blocks to revert the registration")
+	}
+}
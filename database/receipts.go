@@ -0,0 +1,116 @@
+package database
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// ReceiptStatusFailed TXs had their contract execution fail. They paid their fees
+	// and the gas used, but changed nothing else
+	ReceiptStatusFailed  = 0
+	ReceiptStatusSuccess = 1
+)
+
+// Receipt is the outcome of a TX applied in a block.
+type Receipt struct {
+	TxHash      Hash   `json:"tx_hash"`
+	BlockHash   Hash   `json:"block_hash"`
+	BlockNumber uint64 `json:"block_number"`
+	Index       uint   `json:"index"`
+	Status      uint   `json:"status"`
+
+	// Fee charged to the sender, name and gas fees included, of which BurnedFee was burned and Tip paid to the miner
+	Fee       uint `json:"fee"`
+	BurnedFee uint `json:"burned_fee"`
+	Tip       uint `json:"tip"`
+	GasUsed   uint `json:"gas_used"`
+
+	// Base coin balance changes of the sender and recipients, fee included
+	BalanceDeltas map[common.Address]int `json:"balance_deltas"`
+
+	ContractAddress *common.Address `json:"contract_address,omitempty"`
+	Logs            []Log           `json:"logs,omitempty"`
+}
+
+// Log is emitted by a contract with the LOG1 opcode.
+type Log struct {
+	Address common.Address `json:"address"`
+	Topic   uint64         `json:"topic"`
+	Data    uint64         `json:"data"`
+}
+
+func (s *State) Receipt(txHash Hash) (Receipt, bool) {
+	r, ok := s.receipts[txHash]
+
+	return r, ok
+}
+
+// applyTxWithReceipt applies the TX of the block and records its receipt.
+func applyTxWithReceipt(tx SignedTx, index int, b Block, blockHash Hash, s *State) error {
+	accounts := append([]common.Address{tx.From, tx.To}, outputAccounts(tx)...)
+	if tx.IsContractDeploy() {
+		accounts = append(accounts, tx.ContractAddress())
+	}
+	before := make(map[common.Address]uint)
+	for _, acc := range accounts {
+		before[acc] = s.Balances[acc]
+	}
+	gasUsed := s.gasUsed
+	s.txLogs = nil
+
+	err := ApplyTx(tx, s)
+	if err != nil {
+		return err
+	}
+
+	txHash, err := tx.Hash()
+	if err != nil {
+		return err
+	}
+
+	r := Receipt{
+		TxHash:        txHash,
+		BlockHash:     blockHash,
+		BlockNumber:   b.Header.Number,
+		Index:         uint(index),
+		Status:        s.txStatus,
+		Fee:           tx.ChargedFee(b.Header.BaseFee) + tx.NameFee() + (s.gasUsed-gasUsed)*GasPrice,
+		BurnedFee:     b.Header.BaseFee + tx.NameFee(),
+		Tip:           tx.MinerTip(b.Header.BaseFee) + (s.gasUsed-gasUsed)*GasPrice,
+		GasUsed:       s.gasUsed - gasUsed,
+		BalanceDeltas: make(map[common.Address]int),
+		Logs:          s.txLogs,
+	}
+
+	for _, acc := range accounts {
+		if delta := int(s.Balances[acc]) - int(before[acc]); delta != 0 {
+			r.BalanceDeltas[acc] = delta
+		}
+	}
+
+	if tx.IsContractDeploy() {
+		contract := tx.ContractAddress()
+		r.ContractAddress = &contract
+	}
+
+	s.latestReceipts = append(s.latestReceipts, r)
+	s.txLogs = nil
+
+	return nil
+}
+
+// indexReceipts makes the receipts of the latest applied block queryable by TX hash.
+func (s *State) indexReceipts() {
+	for _, r := range s.latestReceipts {
+		s.receipts[r.TxHash] = r
+	}
+}
+
+func outputAccounts(tx SignedTx) []common.Address {
+	accounts := make([]common.Address, len(tx.Outputs))
+	for i, out := range tx.Outputs {
+		accounts[i] = out.To
+	}
+
+	return accounts
+}
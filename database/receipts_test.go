@@ -0,0 +1,89 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestReceipts(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	miner, recipient := common.HexToAddress("0x22"), common.HexToAddress("0x11")
+	now := uint64(time.Now().Unix())
+
+	// logs topic 5 with data 9
+	code := "0x60096005a100"
+	deployGas, callGas := uint(6*GasCodeByte), uint(2*GasDefault+GasLog)
+
+	transfer := signTestTx(t, NewTx(sender, recipient, 10, 1, ""), key)
+	deploy := NewTx(sender, common.Address{}, 0, 2, code)
+	deploy.GasLimit = deployGas
+
+	block := NewBlock(Hash{}, 0, 0, now, miner, []SignedTx{transfer, signTestTx(t, deploy, key)})
+	block.Header.GasUsed = deployGas
+	blockHash, err := s.AddBlock(mineTestBlock(t, block))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := NewTx(sender, deploy.ContractAddress(), 0, 3, "")
+	call.GasLimit = 100
+	signedCall := signTestTx(t, call, key)
+
+	callBlock := NewBlock(blockHash, 1, 0, now+1, miner, []SignedTx{signedCall})
+	callBlock.Header.GasUsed = callGas
+	_, err = s.AddBlock(mineTestBlock(t, callBlock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transferHash, err := transfer.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Receipt{
+		TxHash:        transferHash,
+		BlockHash:     blockHash,
+		BlockNumber:   0,
+		Index:         0,
+		Status:        ReceiptStatusSuccess,
+		Fee:           TxFee,
+		Tip:           TxFee,
+		BalanceDeltas: map[common.Address]int{sender: -int(10 + TxFee), recipient: 10},
+	}
+	receipt, ok := s.Receipt(transferHash)
+	if !ok || !reflect.DeepEqual(receipt, expected) {
+		t.Fatalf("expected the transfer receipt %+v, got %+v", expected, receipt)
+	}
+
+	callHash, err := signedCall.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callReceipt, ok := s.Receipt(callHash)
+	expectedLogs := []Log{{Address: deploy.ContractAddress(), Topic: 5, Data: 9}}
+	if !ok || callReceipt.GasUsed != callGas || !reflect.DeepEqual(callReceipt.Logs, expectedLogs) {
+		t.Fatalf("expected the call receipt to use %d gas and log %+v, got %+v", callGas, expectedLogs, callReceipt)
+	}
+
+	loaded, err := NewStateFromDisk(filepath.Dir(filepath.Dir(s.dbFile.Name())), testMiningDifficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+
+	for _, txHash := range []Hash{transferHash, callHash} {
+		stored, _ := s.Receipt(txHash)
+		reloaded, ok := loaded.Receipt(txHash)
+		if !ok || !reflect.DeepEqual(reloaded, stored) {
+			t.Fatalf("expected the receipt of TX %s to be reloaded from disk, got %+v", txHash.Hex(), reloaded)
+		}
+	}
+}
@@ -1,12 +1,11 @@
 package database
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
-	"sort"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -24,6 +23,62 @@ type State struct {
 	hasGenesisBlock bool
 
 	miningDifficulty uint
+	consensus        Consensus
+	// summed difficulty of the applied blocks, deciding the fork choice
+	totalDifficulty uint64
+
+	// hashes and timestamps of all applied blocks, indexed by height
+	blockHashes        []Hash
+	blockTimes         []uint64
+	maxFutureBlockTime uint64
+	medianTimeBlocks   uint
+	clock              Clock
+	// time of the block whose TXs are being applied, 0 for mempool TXs
+	applyingBlockTime uint64
+
+	maxBlockBytes uint
+	maxBlockTXs   uint
+	minTxFee      uint
+	feeMarket     *FeeMarketConfig
+	nextBaseFee   uint
+
+	// block reward schedule and supply after each applied block
+	emission      EmissionConfig
+	genesisSupply uint
+	supply        []Supply
+
+	// uncle hash -> height of the block including it
+	includedUncles map[Hash]uint64
+
+	maxReorgDepth uint64
+	checkpoints   map[uint64]Hash
+
+	// registered M-of-N accounts by address
+	multisigs map[common.Address]MultisigAccount
+
+	// deployed contracts, the changes each block made to them and the gas used since the last block
+	contracts       map[common.Address]Contract
+	contractJournal map[uint64][]contractChange
+	gasUsed         uint
+
+	// native tokens by symbol and their balances by symbol and holder
+	tokens        map[string]Token
+	tokenBalances map[string]map[common.Address]uint
+
+	// hash-time-locked escrows by the hash of their lock TX
+	htlcs map[Hash]HTLC
+
+	// registered names and the records each block changed
+	names       map[string]NameRecord
+	nameJournal map[uint64][]nameChange
+
+	// receipts of all applied TXs by hash, only the chain state keeps them
+	receipts       map[Hash]Receipt
+	latestReceipts []Receipt
+	// status and logs of the TX being applied, picked up by its receipt
+	txStatus uint
+	txLogs   []Log
+
 	// position of block in file db
 	HashCache   map[string]int64
 	HeightCache map[uint64]int64
@@ -41,21 +96,121 @@ func NewStateFromDisk(dataDir string, miningDifficulty uint) (*State, error) {
 	}
 
 	balances := make(map[common.Address]uint)
+	genesisSupply := uint(0)
 	for account, balance := range gen.Balances {
 		balances[account] = balance
+		genesisSupply += balance
+	}
+
+	err = validateGenesisBlockLimits(gen)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBlockBytes := gen.MaxBlockBytes
+	if maxBlockBytes == 0 {
+		maxBlockBytes = DefaultMaxBlockBytes
+	}
+
+	maxBlockTXs := gen.MaxBlockTXs
+	if maxBlockTXs == 0 {
+		maxBlockTXs = DefaultMaxBlockTXs
+	}
+
+	minTxFee := gen.MinTxFee
+	if minTxFee == 0 {
+		minTxFee = DefaultMinTxFee
+	}
+
+	feeMarket := gen.FeeMarket
+	nextBaseFee := uint(0)
+	if feeMarket != nil {
+		if feeMarket.Elasticity == 0 {
+			feeMarket.Elasticity = DefaultBaseFeeElasticity
+		}
+		if feeMarket.ChangeDenominator == 0 {
+			feeMarket.ChangeDenominator = DefaultBaseFeeChangeDenominator
+		}
+		nextBaseFee = feeMarket.InitialBaseFee
+	}
+
+	emission := EmissionConfig{BlockReward: BlockReward}
+	if gen.Emission != nil {
+		emission = *gen.Emission
+	}
+
+	maxReorgDepth := gen.MaxReorgDepth
+	if maxReorgDepth == 0 {
+		maxReorgDepth = DefaultMaxReorgDepth
+	}
+
+	checkpoints := make(map[uint64]Hash)
+	for number, hash := range hardCodedCheckpoints {
+		checkpoints[number] = hash
+	}
+	for number, hash := range gen.Checkpoints {
+		checkpoints[number] = hash
 	}
 
 	account2nonce := make(map[common.Address]uint)
 
+	consensus, err := NewConsensus(gen.Consensus)
+	if err != nil {
+		return nil, err
+	}
+
 	dbFilepath := getBlocksDbFilePath(dataDir)
 	f, err := os.OpenFile(dbFilepath, os.O_APPEND|os.O_RDWR, 0o600)
 	if err != nil {
 		return nil, err
 	}
 
-	scanner := bufio.NewScanner(f)
+	scanner := newBlockDbScanner(f)
+
+	maxFutureBlockTime := gen.MaxFutureBlockTime
+	if maxFutureBlockTime == 0 {
+		maxFutureBlockTime = DefaultMaxFutureBlockTime
+	}
+
+	medianTimeBlocks := gen.MedianTimeBlocks
+	if medianTimeBlocks == 0 {
+		medianTimeBlocks = DefaultMedianTimeBlocks
+	}
 
-	state := &State{balances, account2nonce, f, Block{}, Hash{}, false, miningDifficulty, map[string]int64{}, map[uint64]int64{}}
+	state := &State{
+		Balances:           balances,
+		Account2Nonce:      account2nonce,
+		dbFile:             f,
+		miningDifficulty:   miningDifficulty,
+		consensus:          consensus,
+		blockHashes:        []Hash{},
+		blockTimes:         []uint64{},
+		maxFutureBlockTime: maxFutureBlockTime,
+		medianTimeBlocks:   medianTimeBlocks,
+		clock:              time.Now,
+		maxBlockBytes:      maxBlockBytes,
+		maxBlockTXs:        maxBlockTXs,
+		minTxFee:           minTxFee,
+		feeMarket:          feeMarket,
+		nextBaseFee:        nextBaseFee,
+		emission:           emission,
+		genesisSupply:      genesisSupply,
+		supply:             []Supply{},
+		includedUncles:     map[Hash]uint64{},
+		maxReorgDepth:      maxReorgDepth,
+		checkpoints:        checkpoints,
+		multisigs:          map[common.Address]MultisigAccount{},
+		contracts:          map[common.Address]Contract{},
+		contractJournal:    map[uint64][]contractChange{},
+		tokens:             map[string]Token{},
+		tokenBalances:      map[string]map[common.Address]uint{},
+		htlcs:              map[Hash]HTLC{},
+		names:              map[string]NameRecord{},
+		nameJournal:        map[uint64][]nameChange{},
+		receipts:           map[Hash]Receipt{},
+		HashCache:          map[string]int64{},
+		HeightCache:        map[uint64]int64{},
+	}
 
 	// set file position
 	filePos := int64(0)
@@ -81,6 +236,7 @@ func NewStateFromDisk(dataDir string, miningDifficulty uint) (*State, error) {
 		if err != nil {
 			return nil, err
 		}
+		state.indexReceipts()
 
 		// set search caches
 		state.HashCache[blockFs.Key.Hex()] = filePos
@@ -92,6 +248,10 @@ func NewStateFromDisk(dataDir string, miningDifficulty uint) (*State, error) {
 		state.hasGenesisBlock = true
 	}
 
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
 	return state, nil
 }
 
@@ -104,7 +264,9 @@ func (s *State) GetForkedBlock(peerBlocks []Block) (Block, error) {
 	prev := Block{}
 	for i, b := range blocks {
 		if !reflect.DeepEqual(b, peerBlocks[i]) {
-			if b.Header.Time < peerBlocks[i].Header.Time {
+			// a PoA block sealed in turn beats one sealed out of turn
+			ours, theirs := blockDifficulty(b), blockDifficulty(peerBlocks[i])
+			if theirs > ours || (theirs == ours && b.Header.Time < peerBlocks[i].Header.Time) {
 				return prev, nil
 			}
 		}
@@ -115,30 +277,99 @@ func (s *State) GetForkedBlock(peerBlocks []Block) (Block, error) {
 }
 
 func (s *State) RemoveBlocks(fromBlock Block) error {
+	err := s.ValidateReorg(fromBlock)
+	if err != nil {
+		return err
+	}
+
 	for !reflect.DeepEqual(s.latestBlock, fromBlock) {
 		filePos, ok := s.HashCache[s.latestBlockHash.Hex()]
 		if !ok {
 			return fmt.Errorf("block not found")
 		}
 
-		for _, tx := range s.latestBlock.TXs {
-			s.Balances[tx.From] += tx.Cost()
-			s.Balances[tx.To] -= tx.Value
+		// revert the TXs latest first as a TX may depend on the tokens an earlier one created
+		for i := len(s.latestBlock.TXs) - 1; i >= 0; i-- {
+			tx := s.latestBlock.TXs[i]
+
+			txHash, err := tx.Hash()
+			if err != nil {
+				return err
+			}
+
+			receipt, ok := s.receipts[txHash]
+			if !ok {
+				return fmt.Errorf("no receipt of TX %s to revert", txHash.Hex())
+			}
+			delete(s.receipts, txHash)
+
+			s.Balances[tx.From] += tx.ChargedFee(s.latestBlock.Header.BaseFee) + tx.NameFee() + receipt.GasUsed*GasPrice
 			s.Account2Nonce[tx.From]--
+
+			if receipt.Status == ReceiptStatusFailed {
+				continue
+			}
+
+			revertTokenOp(tx, s)
+
+			err = revertHTLCOp(tx, s)
+			if err != nil {
+				return err
+			}
+
+			s.Balances[tx.From] += tx.TotalValue()
+			if to, ok := tx.valueRecipient(); ok {
+				s.Balances[to] -= tx.Value
+			}
+			for _, out := range tx.Outputs {
+				s.Balances[out.To] -= out.Value
+			}
+
+			if tx.RegisterMultisig != nil {
+				delete(s.multisigs, tx.To)
+			}
 		}
 
-		s.Balances[s.latestBlock.Header.Miner] -= BlockReward
-		s.Balances[s.latestBlock.Header.Miner] -= uint(len(s.latestBlock.TXs)) * TxFee
+		for _, credit := range s.blockRewards(s.latestBlock, s.supplyBeforeLatest().Circulating) {
+			s.Balances[credit.account] -= credit.amount
+		}
+		s.Balances[s.latestBlock.Header.Miner] -= blockTips(s.latestBlock)
+
+		for _, uncle := range s.latestBlock.Header.Uncles {
+			uncleHash, err := uncle.Hash()
+			if err != nil {
+				return err
+			}
+			delete(s.includedUncles, uncleHash)
+		}
+
+		s.revertContracts(s.latestBlock.Header.Number)
+		s.revertNames(s.latestBlock.Header.Number)
+
+		err = s.consensus.Revert(s.latestBlock)
+		if err != nil {
+			return err
+		}
+
+		s.blockHashes = s.blockHashes[:len(s.blockHashes)-1]
+		s.blockTimes = s.blockTimes[:len(s.blockTimes)-1]
+		s.totalDifficulty -= blockDifficulty(s.latestBlock)
+		s.supply = s.supply[:len(s.supply)-1]
 
 		parent, err := GetBlockByHeightOrHashByFileName(s, 0, s.latestBlock.Header.Parent.Hex(), s.dbFile.Name())
 		if err != nil {
 			return err
 		}
 
-		s.latestBlock = parent.Value
-		s.latestBlockHash = parent.Key
+		s.nextBaseFee, err = s.calcNextBaseFee(parent.Value)
+		if err != nil {
+			return err
+		}
+
 		delete(s.HashCache, s.latestBlockHash.Hex())
 		delete(s.HeightCache, s.latestBlock.Header.Number)
+		s.latestBlock = parent.Value
+		s.latestBlockHash = parent.Key
 
 		// truncate dbfile
 		err = s.dbFile.Truncate(filePos)
@@ -163,7 +394,7 @@ func (s *State) AddBlock(b Block) (Hash, error) {
 		return Hash{}, err
 	}
 
-	blockFs := BlockFS{blockHash, b}
+	blockFs := BlockFS{Key: blockHash, Value: b, Receipts: pendingState.latestReceipts}
 
 	blockFsJson, err := json.Marshal(blockFs)
 	if err != nil {
@@ -173,9 +404,9 @@ func (s *State) AddBlock(b Block) (Hash, error) {
 	fmt.Printf("\nPersisting new Block to disk:\n")
 	fmt.Printf("\t%s\n", blockFsJson)
 
-	// get file pos for cache
+	// get file pos for cache, where the block starts as RemoveBlocks truncates the file there
 	fs, _ := s.dbFile.Stat()
-	filePos := fs.Size() + 1
+	filePos := fs.Size()
 
 	_, err = s.dbFile.Write(append(blockFsJson, '\n'))
 	if err != nil {
@@ -192,6 +423,23 @@ func (s *State) AddBlock(b Block) (Hash, error) {
 	s.latestBlock = b
 	s.hasGenesisBlock = true
 	s.miningDifficulty = pendingState.miningDifficulty
+	s.consensus = pendingState.consensus
+	s.blockHashes = pendingState.blockHashes
+	s.blockTimes = pendingState.blockTimes
+	s.totalDifficulty = pendingState.totalDifficulty
+	s.supply = pendingState.supply
+	s.includedUncles = pendingState.includedUncles
+	s.nextBaseFee = pendingState.nextBaseFee
+	s.multisigs = pendingState.multisigs
+	s.contracts = pendingState.contracts
+	s.contractJournal = pendingState.contractJournal
+	s.tokens = pendingState.tokens
+	s.tokenBalances = pendingState.tokenBalances
+	s.htlcs = pendingState.htlcs
+	s.names = pendingState.names
+	s.nameJournal = pendingState.nameJournal
+	s.latestReceipts = pendingState.latestReceipts
+	s.indexReceipts()
 
 	return blockHash, nil
 }
@@ -216,6 +464,10 @@ func (s *State) GetNextAccountNonce(account common.Address) uint {
 	return s.Account2Nonce[account] + 1
 }
 
+func (s *State) Consensus() Consensus {
+	return s.consensus
+}
+
 func (s *State) ChangeMiningDifficulty(newDifficulty uint) {
 	s.miningDifficulty = newDifficulty
 }
@@ -228,6 +480,43 @@ func (s *State) Copy() State {
 	c.Balances = make(map[common.Address]uint)
 	c.Account2Nonce = make(map[common.Address]uint)
 	c.miningDifficulty = s.miningDifficulty
+	c.consensus = s.consensus.Copy()
+	c.blockHashes = append([]Hash{}, s.blockHashes...)
+	c.blockTimes = append([]uint64{}, s.blockTimes...)
+	c.totalDifficulty = s.totalDifficulty
+	c.maxFutureBlockTime = s.maxFutureBlockTime
+	c.medianTimeBlocks = s.medianTimeBlocks
+	c.clock = s.clock
+	c.maxBlockBytes = s.maxBlockBytes
+	c.maxBlockTXs = s.maxBlockTXs
+	c.minTxFee = s.minTxFee
+	c.feeMarket = s.feeMarket
+	c.nextBaseFee = s.nextBaseFee
+	c.emission = s.emission
+	c.genesisSupply = s.genesisSupply
+	c.supply = append([]Supply{}, s.supply...)
+	c.includedUncles = make(map[Hash]uint64)
+
+	for uncleHash, number := range s.includedUncles {
+		c.includedUncles[uncleHash] = number
+	}
+
+	c.maxReorgDepth = s.maxReorgDepth
+	c.checkpoints = s.checkpoints
+	c.multisigs = make(map[common.Address]MultisigAccount)
+
+	for acc, m := range s.multisigs {
+		c.multisigs[acc] = m
+	}
+
+	s.copyContracts(&c)
+	s.copyTokens(&c)
+	s.copyNames(&c)
+	c.htlcs = make(map[Hash]HTLC)
+
+	for id, htlc := range s.htlcs {
+		c.htlcs[id] = htlc
+	}
 
 	for acc, balance := range s.Balances {
 		c.Balances[acc] = balance
@@ -255,53 +544,171 @@ func applyBlock(b Block, s *State) error {
 		return fmt.Errorf("next block parent hash must be '%x' not '%x'", s.latestBlockHash, b.Header.Parent)
 	}
 
-	hash, err := b.Hash()
+	err := validateBlockTime(b, s)
 	if err != nil {
 		return err
 	}
 
-	if !IsBlockHashValid(hash, s.miningDifficulty) {
-		return fmt.Errorf("invalid block hash %x", hash)
+	err = validateBlockLimits(b, s)
+	if err != nil {
+		return err
 	}
 
-	err = applyTXs(b.TXs, s)
+	if b.Header.BaseFee != s.nextBaseFee {
+		return fmt.Errorf("block base fee must be '%d' not '%d'", s.nextBaseFee, b.Header.BaseFee)
+	}
+
+	err = validatePayloadHash(b)
 	if err != nil {
 		return err
 	}
 
-	s.Balances[b.Header.Miner] += BlockReward
-	s.Balances[b.Header.Miner] += uint(len(b.TXs)) * TxFee
+	err = s.consensus.VerifySeal(b, s)
+	if err != nil {
+		return err
+	}
+
+	blockHash, err := b.Hash()
+	if err != nil {
+		return err
+	}
+
+	err = validateCheckpoint(b.Header.Number, blockHash, s)
+	if err != nil {
+		return err
+	}
+
+	err = validateUncles(b, s)
+	if err != nil {
+		return err
+	}
+
+	s.applyingBlockTime = b.Header.Time
+	s.gasUsed = 0
+	err = applyTXs(b, blockHash, s)
+	s.applyingBlockTime = 0
+	if err != nil {
+		return err
+	}
+
+	if b.Header.GasUsed != s.gasUsed {
+		return fmt.Errorf("block gas used must be '%d' not '%d'", s.gasUsed, b.Header.GasUsed)
+	}
+	s.gasUsed = 0
+	s.pruneContractJournal(b.Header.Number)
+	s.pruneNameJournal(b.Header.Number)
+
+	minted := uint(0)
+	for _, credit := range s.blockRewards(b, s.LatestSupply().Circulating) {
+		s.Balances[credit.account] += credit.amount
+		minted += credit.amount
+	}
+	s.Balances[b.Header.Miner] += blockTips(b)
+
+	for _, uncle := range b.Header.Uncles {
+		uncleHash, err := uncle.Hash()
+		if err != nil {
+			return err
+		}
+		s.includedUncles[uncleHash] = b.Header.Number
+	}
+
+	s.blockHashes = append(s.blockHashes, blockHash)
+	s.blockTimes = append(s.blockTimes, b.Header.Time)
+	s.totalDifficulty += blockDifficulty(b)
+	s.recordSupply(b, minted, blockFees(b), b.Header.BaseFee*uint(len(b.TXs))+blockNameFees(b))
+
+	s.nextBaseFee, err = s.calcNextBaseFee(b)
+	if err != nil {
+		return err
+	}
+
+	err = s.consensus.Finalize(b)
+	if err != nil {
+		return err
+	}
+	s.pruneConsensusHistory(b.Header.Number)
 
 	return nil
 }
 
-func applyTXs(txs []SignedTx, s *State) error {
-	sort.Slice(txs, func(i, j int) bool {
-		return txs[i].Time < txs[j].Time
-	})
+// applyTXs applies the TXs in the exact order the miner stored them in the block.
+// The block is hashed with this order, so it must never be rearranged here.
+func applyTXs(b Block, blockHash Hash, s *State) error {
+	s.latestReceipts = make([]Receipt, 0, len(b.TXs))
 
-	for _, tx := range txs {
-		err := ApplyTx(tx, s)
+	for i, tx := range b.TXs {
+		err := applyTxWithReceipt(tx, i, b, blockHash, s)
 		if err != nil {
-			return err
+			return fmt.Errorf("block TX %d: %s", i, err.Error())
 		}
 	}
 
 	return nil
 }
 
+// ApplicableTXs applies the TXs to a copy of the state as a block sealed at the
+// given time would, skipping the ones that fail. It returns the TXs left and the
+// gas they use, which the miner puts in the block header.
+func (s *State) ApplicableTXs(txs []SignedTx, time uint64) ([]SignedTx, uint) {
+	c := s.Copy()
+	c.applyingBlockTime = time
+	c.gasUsed = 0
+
+	applicable := make([]SignedTx, 0, len(txs))
+	for _, tx := range txs {
+		err := ApplyTx(tx, &c)
+		if err != nil {
+			txHash, _ := tx.Hash()
+			fmt.Printf("Skipping TX %s: %s\n", txHash.Hex(), err)
+			continue
+		}
+
+		applicable = append(applicable, tx)
+	}
+
+	return applicable, c.gasUsed
+}
+
 func ApplyTx(tx SignedTx, s *State) error {
 	err := ValidateTx(tx, s)
 	if err != nil {
 		return err
 	}
 
-	s.Balances[tx.From] -= tx.Cost()
-	s.Balances[tx.To] += tx.Value
+	gasUsed := s.gasUsed
+	s.txStatus, err = applyContractTx(tx, s)
+	if err != nil {
+		return err
+	}
+	fees := tx.ChargedFee(s.nextBaseFee) + tx.NameFee() + (s.gasUsed-gasUsed)*GasPrice
+
+	// a failed TX only pays its fees and burns its nonce
+	if s.txStatus == ReceiptStatusFailed {
+		s.Balances[tx.From] -= fees
+		s.Account2Nonce[tx.From] = tx.Nonce
+
+		return nil
+	}
+
+	s.Balances[tx.From] -= tx.TotalValue() + fees
+	if to, ok := tx.valueRecipient(); ok {
+		s.Balances[to] += tx.Value
+	}
+	for _, out := range tx.Outputs {
+		s.Balances[out.To] += out.Value
+	}
 
 	s.Account2Nonce[tx.From] = tx.Nonce
 
-	return nil
+	if tx.RegisterMultisig != nil {
+		s.multisigs[tx.To] = *tx.RegisterMultisig
+	}
+
+	applyTokenOp(tx, s)
+	applyNameOp(tx, s)
+
+	return applyHTLCOp(tx, s)
 }
 
 func ValidateTx(tx SignedTx, s *State) error {
@@ -314,6 +721,59 @@ func ValidateTx(tx SignedTx, s *State) error {
 		return fmt.Errorf("wrong TX. Sender '%s' is forged", tx.From.String())
 	}
 
+	err = validateTxTimeLock(tx, s)
+	if err != nil {
+		return err
+	}
+
+	err = validateSingleOp(tx, s)
+	if err != nil {
+		return err
+	}
+
+	err = validateMultisig(tx, s)
+	if err != nil {
+		return err
+	}
+
+	err = validateBatch(tx)
+	if err != nil {
+		return err
+	}
+
+	err = validateTxCost(tx)
+	if err != nil {
+		return err
+	}
+
+	err = validateContractTx(tx, s)
+	if err != nil {
+		return err
+	}
+
+	err = validateTokenOp(tx, s)
+	if err != nil {
+		return err
+	}
+
+	err = validateHTLCOp(tx, s)
+	if err != nil {
+		return err
+	}
+
+	err = validateNameOp(tx, s)
+	if err != nil {
+		return err
+	}
+
+	if tx.FeeCap() < s.minTxFee {
+		return fmt.Errorf("wrong TX. Fee %d is below the minimum fee %d", tx.FeeCap(), s.minTxFee)
+	}
+
+	if tx.FeeCap() < s.nextBaseFee {
+		return fmt.Errorf("wrong TX. Max fee %d is below the base fee %d", tx.FeeCap(), s.nextBaseFee)
+	}
+
 	expectedNonce := s.GetNextAccountNonce(tx.From)
 	if tx.Nonce != expectedNonce {
 		return fmt.Errorf("wrong TX. Sender '%s' next nonce must be '%d', not '%d'", tx.From.String(), expectedNonce, tx.Nonce)
@@ -338,7 +798,7 @@ func (s *State) GetBlocks() ([]Block, error) {
 		return nil, err
 	}
 
-	scanner := bufio.NewScanner(s.dbFile)
+	scanner := newBlockDbScanner(s.dbFile)
 
 	for scanner.Scan() {
 		var blockFs BlockFS
@@ -350,5 +810,5 @@ func (s *State) GetBlocks() ([]Block, error) {
 		blocks = append(blocks, blockFs.Value)
 	}
 
-	return blocks, nil
+	return blocks, scanner.Err()
 }
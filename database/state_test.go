@@ -0,0 +1,260 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testMiningDifficulty = 1
+
+// newTestState loads a state from a fresh data dir whose genesis funds a new account.
+func newTestState(t *testing.T) (*State, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	return newTestStateWithGenesis(t, Genesis{})
+}
+
+// newTestStateWithGenesis is newTestState with the given genesis settings.
+func newTestStateWithGenesis(t *testing.T, gen Genesis) (*State, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen.Symbol = "ETH"
+	gen.Balances = map[common.Address]uint{crypto.PubkeyToAddress(key.PublicKey): 1000000}
+
+	genesis, err := json.Marshal(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataDir := t.TempDir()
+	err = InitDataDirIfNotExists(dataDir, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewStateFromDisk(dataDir, testMiningDifficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+
+	return s, key
+}
+
+func signTestTx(t *testing.T, tx Tx, key *ecdsa.PrivateKey) SignedTx {
+	t.Helper()
+
+	txJson, err := tx.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txHash := sha256.Sum256(txJson)
+	sig, err := crypto.Sign(txHash[:], key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewSignedTx(tx, sig)
+}
+
+// mineTestBlock finds a nonce sealing the block at the test mining difficulty.
+func mineTestBlock(t *testing.T, b Block) Block {
+	t.Helper()
+
+	for nonce := uint32(0); ; nonce++ {
+		b.Header.Nonce = nonce
+
+		hash, err := b.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if IsBlockHashValid(hash, testMiningDifficulty) {
+			return b
+		}
+	}
+}
+
+func TestApplyTXsInStoredOrder(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	recipient := common.HexToAddress("0x11")
+	now := uint64(time.Now().Unix())
+
+	// both TXs are created in the same second, so only the stored order tells them apart
+	first := NewTx(sender, recipient, 10, 1, "")
+	second := NewTx(sender, recipient, 20, 2, "")
+	first.Time, second.Time = now, now
+
+	txs := []SignedTx{signTestTx(t, first, key), signTestTx(t, second, key)}
+	block := mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, sender, txs))
+
+	blockHash, err := block.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	swapped := mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, sender, []SignedTx{txs[1], txs[0]}))
+	_, err = s.AddBlock(swapped)
+	if err == nil {
+		t.Fatal("expected the block with the TXs in reverse nonce order to be rejected")
+	}
+
+	addedHash, err := s.AddBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addedHash != blockHash {
+		t.Fatalf("expected the block hash %x to be unchanged, got %x", blockHash, addedHash)
+	}
+
+	blocks, err := s.GetBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blocks) != 1 || !reflect.DeepEqual(blocks[0].TXs, txs) {
+		t.Fatalf("expected the stored block to keep its TXs order, got %+v", blocks)
+	}
+
+	if s.Balances[recipient] != 30 || s.Account2Nonce[sender] != 2 {
+		t.Fatalf("expected both TXs to be applied, recipient balance is %d and sender nonce %d", s.Balances[recipient], s.Account2Nonce[sender])
+	}
+}
+
+func TestNewStateFromDiskLoadsLargeBlocks(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	now := uint64(time.Now().Unix())
+
+	txs := make([]SignedTx, 300)
+	for i := range txs {
+		tx := NewTx(sender, common.HexToAddress("0x11"), 1, uint(i+1), "")
+		txs[i] = signTestTx(t, tx, key)
+	}
+
+	block := mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, sender, txs))
+	blockHash, err := s.AddBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := block.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size <= 64*1024 {
+		t.Fatalf("expected the block to be larger than the default scanner buffer, it is %d bytes", size)
+	}
+
+	dataDir := filepath.Dir(filepath.Dir(s.dbFile.Name()))
+	loaded, err := NewStateFromDisk(dataDir, testMiningDifficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+
+	if loaded.LatestBlockHash() != blockHash {
+		t.Fatalf("expected the reloaded chain to end with block %x, got %x", blockHash, loaded.LatestBlockHash())
+	}
+
+	fetched, err := GetBlockByHeightOrHash(loaded, 0, blockHash.Hex(), dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched.Value.TXs) != len(txs) || len(fetched.Receipts) != len(txs) {
+		t.Fatalf("expected the block with its %d TXs and receipts, got %d and %d", len(txs), len(fetched.Value.TXs), len(fetched.Receipts))
+	}
+}
+
+func TestAddBlockCachesFilePositions(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	now := uint64(time.Now().Unix())
+
+	parent := Hash{}
+	for number := uint64(0); number < 2; number++ {
+		tx := NewTx(sender, common.HexToAddress("0x11"), 1, uint(number+1), "")
+		block := mineTestBlock(t, NewBlock(parent, number, 0, now+number, sender, []SignedTx{signTestTx(t, tx, key)}))
+
+		blockHash, err := s.AddBlock(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parent = blockHash
+
+		byHeight, err := GetBlockByHeightOrHashByFileName(s, number, "", s.dbFile.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		byHash, err := GetBlockByHeightOrHashByFileName(s, 0, blockHash.Hex(), s.dbFile.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if byHeight.Key != blockHash || byHash.Key != blockHash {
+			t.Fatalf("expected block %d to be read from its cached position, got %x and %x", number, byHeight.Key, byHash.Key)
+		}
+	}
+}
+
+func TestRemoveBlocksTruncatesAtBlockOffset(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	dataDir := filepath.Dir(filepath.Dir(s.dbFile.Name()))
+	now := uint64(time.Now().Unix())
+
+	hashes := make([]Hash, 3)
+	parent := Hash{}
+	for number := range hashes {
+		tx := NewTx(sender, common.HexToAddress("0x11"), 1, uint(number+1), "")
+		block := mineTestBlock(t, NewBlock(parent, uint64(number), 0, now+uint64(number), sender, []SignedTx{signTestTx(t, tx, key)}))
+
+		blockHash, err := s.AddBlock(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[number], parent = blockHash, blockHash
+	}
+
+	err := s.RemoveBlocks(storedTestBlock(t, s, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a reorg replaces the removed blocks with the other chain's
+	tx := NewTx(sender, common.HexToAddress("0x12"), 1, 2, "")
+	replacement := mineTestBlock(t, NewBlock(hashes[0], 1, 0, now+3, sender, []SignedTx{signTestTx(t, tx, key)}))
+	replacementHash, err := s.AddBlock(replacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewStateFromDisk(dataDir, testMiningDifficulty)
+	if err != nil {
+		t.Fatalf("expected the block DB to load after the reorg. %s", err)
+	}
+	defer loaded.Close()
+
+	if loaded.LatestBlockHash() != replacementHash || loaded.Balances[common.HexToAddress("0x11")] != 1 || loaded.Balances[common.HexToAddress("0x12")] != 1 {
+		t.Fatalf("expected the reloaded chain to end with the replacement block %x, got %x", replacementHash, loaded.LatestBlockHash())
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EmissionConfig is the genesis configured block reward schedule. The reward
+// starts at BlockReward and halves every HalvingInterval blocks without dropping
+// below MinReward. When MaxSupply is set no reward is minted past it.
+type EmissionConfig struct {
+	BlockReward     uint   `json:"block_reward"`
+	HalvingInterval uint64 `json:"halving_interval"`
+	MinReward       uint   `json:"min_reward"`
+	MaxSupply       uint   `json:"max_supply"`
+}
+
+// Supply after the block at Height. BlockReward counts every coin the block minted, uncle rewards included.
+type Supply struct {
+	Height      uint64 `json:"height"`
+	BlockReward uint   `json:"block_reward"`
+	Minted      uint   `json:"total_minted"`
+	Fees        uint   `json:"total_fees"`
+	Burned      uint   `json:"total_burned"`
+	Circulating uint   `json:"circulating_supply"`
+}
+
+// scheduledBlockReward returns the reward the schedule assigns to the block at the given height.
+func (e EmissionConfig) scheduledBlockReward(height uint64) uint {
+	reward := e.BlockReward
+
+	if e.HalvingInterval > 0 {
+		halvings := height / e.HalvingInterval
+		if halvings >= 64 {
+			reward = 0
+		} else {
+			reward >>= halvings
+		}
+	}
+
+	if reward < e.MinReward {
+		reward = e.MinReward
+	}
+
+	return reward
+}
+
+type rewardCredit struct {
+	account common.Address
+	amount  uint
+}
+
+// blockRewards returns the coins minted by the block on top of the given circulating
+// supply: the block reward to its miner plus, for every included uncle, a reduced
+// reward to the uncle's miner and an inclusion reward to the block's miner.
+// Nothing is minted past the max supply.
+func (s *State) blockRewards(b Block, circulating uint) []rewardCredit {
+	reward := s.emission.scheduledBlockReward(b.Header.Number)
+
+	credits := []rewardCredit{{b.Header.Miner, reward}}
+	for _, uncle := range b.Header.Uncles {
+		credits = append(credits, rewardCredit{uncle.Miner, uncleReward(reward, b.Header.Number-uncle.Number)})
+		credits = append(credits, rewardCredit{b.Header.Miner, reward / UncleInclusionRewardDivisor})
+	}
+
+	if s.emission.MaxSupply > 0 {
+		for i := range credits {
+			remaining := uint(0)
+			if circulating < s.emission.MaxSupply {
+				remaining = s.emission.MaxSupply - circulating
+			}
+
+			if credits[i].amount > remaining {
+				credits[i].amount = remaining
+			}
+			circulating += credits[i].amount
+		}
+	}
+
+	return credits
+}
+
+// LatestSupply returns the supply after the latest block, or the genesis allocation if there are no blocks yet.
+func (s *State) LatestSupply() Supply {
+	if len(s.supply) == 0 {
+		return Supply{Circulating: s.genesisSupply}
+	}
+
+	return s.supply[len(s.supply)-1]
+}
+
+// supplyBeforeLatest returns the supply the latest block was applied on top of.
+func (s *State) supplyBeforeLatest() Supply {
+	if len(s.supply) < 2 {
+		return Supply{Circulating: s.genesisSupply}
+	}
+
+	return s.supply[len(s.supply)-2]
+}
+
+// SupplyAt returns the supply after the block at the given height.
+func (s *State) SupplyAt(height uint64) (Supply, error) {
+	if height >= uint64(len(s.supply)) {
+		return Supply{}, fmt.Errorf("invalid height: '%v'", height)
+	}
+
+	return s.supply[height], nil
+}
+
+func (s *State) recordSupply(b Block, reward, fees, burned uint) {
+	latest := s.LatestSupply()
+
+	s.supply = append(s.supply, Supply{
+		Height:      b.Header.Number,
+		BlockReward: reward,
+		Minted:      latest.Minted + reward,
+		Fees:        latest.Fees + fees,
+		Burned:      latest.Burned + burned,
+		Circulating: latest.Circulating + reward - burned,
+	})
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBlockRewardHalvesUpToMaxSupply(t *testing.T) {
+	// the rewards halve every 2 blocks down to 30, and the last one is cut to reach the max supply
+	s, _ := newTestStateWithGenesis(t, Genesis{Emission: &EmissionConfig{
+		BlockReward:     100,
+		HalvingInterval: 2,
+		MinReward:       30,
+		MaxSupply:       1000000 + 100 + 100 + 50 + 50 + 20,
+	}})
+	miner := common.HexToAddress("0x22")
+	now := uint64(time.Now().Unix())
+
+	rewards := []uint{100, 100, 50, 50, 20, 0}
+
+	parent := Hash{}
+	for number := range rewards {
+		blockHash, err := s.AddBlock(mineTestBlock(t, NewBlock(parent, uint64(number), 0, now+uint64(number), miner, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parent = blockHash
+	}
+
+	minted := uint(0)
+	for number, reward := range rewards {
+		minted += reward
+
+		supply, err := s.SupplyAt(uint64(number))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if supply.BlockReward != reward || supply.Minted != minted || supply.Circulating != 1000000+minted {
+			t.Fatalf("expected block %d to mint %d, %d in total, got supply %+v", number, reward, minted, supply)
+		}
+	}
+
+	if s.Balances[miner] != minted {
+		t.Fatalf("expected the miner to earn %d, balance is %d", minted, s.Balances[miner])
+	}
+
+	err := s.RemoveBlocks(storedTestBlock(t, s, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[miner] != 300 || s.LatestSupply().Circulating != 1000000+300 {
+		t.Fatalf("expected removing the blocks to revert their rewards, balance is %d and supply %+v", s.Balances[miner], s.LatestSupply())
+	}
+}
@@ -0,0 +1,59 @@
+package database
+
+import "fmt"
+
+// LockTimeThreshold separates the two meanings of ValidAfter and ValidUntil:
+// values below it are block heights, values from it on are unix timestamps.
+const LockTimeThreshold = 500000000
+
+// IsPremature reports whether the TX can't be mined yet in a block with the given number and time.
+func (t Tx) IsPremature(number, time uint64) bool {
+	if t.ValidAfter == 0 {
+		return false
+	}
+
+	return lockTimeValue(t.ValidAfter, number, time) < t.ValidAfter
+}
+
+// IsExpired reports whether the TX can no longer be mined in a block with the given number and time.
+func (t Tx) IsExpired(number, time uint64) bool {
+	if t.ValidUntil == 0 {
+		return false
+	}
+
+	return lockTimeValue(t.ValidUntil, number, time) > t.ValidUntil
+}
+
+// lockTimeValue picks the block number or time, whichever the lock is expressed in.
+func lockTimeValue(lock, number, time uint64) uint64 {
+	if lock < LockTimeThreshold {
+		return number
+	}
+
+	return time
+}
+
+// nextTxTime is the time of the block being applied or, for mempool TXs, the local time.
+func (s *State) nextTxTime() uint64 {
+	if s.applyingBlockTime == 0 {
+		return uint64(s.clock().Unix())
+	}
+
+	return s.applyingBlockTime
+}
+
+// validateTxTimeLock checks the TX against the next block at nextTxTime.
+func validateTxTimeLock(tx SignedTx, s *State) error {
+	number := s.NextBlockNumber()
+	time := s.nextTxTime()
+
+	if tx.IsPremature(number, time) {
+		return fmt.Errorf("wrong TX. TX is not valid before %d", tx.ValidAfter)
+	}
+
+	if tx.IsExpired(number, time) {
+		return fmt.Errorf("wrong TX. TX expired after %d", tx.ValidUntil)
+	}
+
+	return nil
+}
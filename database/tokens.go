@@ -0,0 +1,157 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	TokenOpCreate   = "create"
+	TokenOpTransfer = "transfer"
+	TokenOpBurn     = "burn"
+)
+
+var tokenSymbolRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+
+// TokenOp makes the TX create a token with the Amount as its supply, or transfer
+// or burn an Amount of the sender's tokens, next to any base coin Value it sends.
+type TokenOp struct {
+	Type   string `json:"type"`
+	Symbol string `json:"symbol"`
+	Amount uint   `json:"amount"`
+}
+
+type Token struct {
+	Symbol string         `json:"symbol"`
+	Issuer common.Address `json:"issuer"`
+	Supply uint           `json:"supply"`
+}
+
+func (s *State) Tokens() []Token {
+	tokens := make([]Token, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Symbol < tokens[j].Symbol
+	})
+
+	return tokens
+}
+
+// TokenBalances returns the account's balance of each token it holds.
+func (s *State) TokenBalances(acc common.Address) map[string]uint {
+	balances := make(map[string]uint)
+	for symbol, holders := range s.tokenBalances {
+		if balance := holders[acc]; balance > 0 {
+			balances[symbol] = balance
+		}
+	}
+
+	return balances
+}
+
+func validateTokenOp(tx SignedTx, s *State) error {
+	op := tx.Token
+	if op == nil {
+		return nil
+	}
+
+	if op.Amount == 0 {
+		return fmt.Errorf("wrong TX. Token %s amount must be greater than 0", op.Type)
+	}
+
+	_, exists := s.tokens[op.Symbol]
+
+	switch op.Type {
+	case TokenOpCreate:
+		if !tokenSymbolRegexp.MatchString(op.Symbol) {
+			return fmt.Errorf("wrong TX. Token symbol '%s' must be 2 to 10 uppercase letters or digits", op.Symbol)
+		}
+
+		if exists {
+			return fmt.Errorf("wrong TX. Token '%s' already exists", op.Symbol)
+		}
+
+	case TokenOpTransfer, TokenOpBurn:
+		if !exists {
+			return fmt.Errorf("wrong TX. Token '%s' doesn't exist", op.Symbol)
+		}
+
+		balance := s.tokenBalances[op.Symbol][tx.From]
+		if op.Amount > balance {
+			return fmt.Errorf("wrong TX. Sender '%s' %s balance is %d. Tx %s is %d", tx.From.String(), op.Symbol, balance, op.Type, op.Amount)
+		}
+
+	default:
+		return fmt.Errorf("wrong TX. Unknown token operation '%s'", op.Type)
+	}
+
+	return nil
+}
+
+func applyTokenOp(tx SignedTx, s *State) {
+	op := tx.Token
+	if op == nil {
+		return
+	}
+
+	switch op.Type {
+	case TokenOpCreate:
+		s.tokens[op.Symbol] = Token{Symbol: op.Symbol, Issuer: tx.From, Supply: op.Amount}
+		s.tokenBalances[op.Symbol] = map[common.Address]uint{tx.From: op.Amount}
+
+	case TokenOpTransfer:
+		s.tokenBalances[op.Symbol][tx.From] -= op.Amount
+		s.tokenBalances[op.Symbol][tx.To] += op.Amount
+
+	case TokenOpBurn:
+		s.tokenBalances[op.Symbol][tx.From] -= op.Amount
+		token := s.tokens[op.Symbol]
+		token.Supply -= op.Amount
+		s.tokens[op.Symbol] = token
+	}
+}
+
+// revertTokenOp undoes applyTokenOp. The block's TXs must be reverted latest first.
+func revertTokenOp(tx SignedTx, s *State) {
+	op := tx.Token
+	if op == nil {
+		return
+	}
+
+	switch op.Type {
+	case TokenOpCreate:
+		delete(s.tokens, op.Symbol)
+		delete(s.tokenBalances, op.Symbol)
+
+	case TokenOpTransfer:
+		s.tokenBalances[op.Symbol][tx.To] -= op.Amount
+		s.tokenBalances[op.Symbol][tx.From] += op.Amount
+
+	case TokenOpBurn:
+		s.tokenBalances[op.Symbol][tx.From] += op.Amount
+		token := s.tokens[op.Symbol]
+		token.Supply += op.Amount
+		s.tokens[op.Symbol] = token
+	}
+}
+
+func (s *State) copyTokens(c *State) {
+	c.tokens = make(map[string]Token)
+	for symbol, token := range s.tokens {
+		c.tokens[symbol] = token
+	}
+
+	c.tokenBalances = make(map[string]map[common.Address]uint)
+	for symbol, holders := range s.tokenBalances {
+		c.tokenBalances[symbol] = make(map[common.Address]uint)
+		for acc, balance := range holders {
+			c.tokenBalances[symbol][acc] = balance
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestTokenOps(t *testing.T) {
+	s, key := newTestState(t)
+	issuer := crypto.PubkeyToAddress(key.PublicKey)
+	miner, holder := common.HexToAddress("0x22"), common.HexToAddress("0x11")
+	now := uint64(time.Now().Unix())
+
+	parent, err := s.AddBlock(mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, miner, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenTx := func(to common.Address, nonce uint, op TokenOp) SignedTx {
+		tx := NewTx(issuer, to, 0, nonce, "")
+		tx.Token = &op
+
+		return signTestTx(t, tx, key)
+	}
+
+	txs := []SignedTx{
+		tokenTx(issuer, 1, TokenOp{Type: TokenOpCreate, Symbol: "TOK", Amount: 100}),
+		tokenTx(holder, 2, TokenOp{Type: TokenOpTransfer, Symbol: "TOK", Amount: 30}),
+		tokenTx(issuer, 3, TokenOp{Type: TokenOpBurn, Symbol: "TOK", Amount: 20}),
+	}
+	_, err = s.AddBlock(mineTestBlock(t, NewBlock(parent, 1, 0, now+1, miner, txs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.TokenBalances(issuer)["TOK"] != 50 || s.TokenBalances(holder)["TOK"] != 30 || s.Tokens()[0].Supply != 80 {
+		t.Fatalf("expected 50 TOK for the issuer, 30 for the holder and a supply of 80, got %v, %v and %+v", s.TokenBalances(issuer), s.TokenBalances(holder), s.Tokens())
+	}
+
+	tests := []struct {
+		name string
+		op   TokenOp
+	}{
+		{"existing token", TokenOp{Type: TokenOpCreate, Symbol: "TOK", Amount: 100}},
+		{"invalid symbol", TokenOp{Type: TokenOpCreate, Symbol: "tok", Amount: 100}},
+		{"unknown token", TokenOp{Type: TokenOpTransfer, Symbol: "NOPE", Amount: 1}},
+		{"transfer above the balance", TokenOp{Type: TokenOpTransfer, Symbol: "TOK", Amount: 51}},
+		{"zero amount", TokenOp{Type: TokenOpBurn, Symbol: "TOK", Amount: 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if ValidateTx(tokenTx(holder, 4, test.op), s) == nil {
+				t.Fatal("expected the token op to be rejected")
+			}
+		})
+	}
+
+	err = s.RemoveBlocks(storedTestBlock(t, s, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Tokens()) != 0 || len(s.TokenBalances(holder)) != 0 {
+		t.Fatalf("expected removing the block to revert its token ops, tokens are %+v", s.Tokens())
+	}
+}
@@ -3,7 +3,10 @@ package database
 import (
 	"crypto/elliptic"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -18,30 +21,140 @@ type Tx struct {
 	From  common.Address `json:"from"`
 	To    common.Address `json:"to"`
 	Value uint           `json:"value"`
+	Fee   uint           `json:"fee,omitempty"`
 	Nonce uint           `json:"nonce"`
 	Data  string         `json:"data"`
 	Time  uint64         `json:"time"`
+
+	// Fee market TXs pay the block's base fee, which is burned, plus up to
+	// PriorityFee to the miner, never more than MaxFee in total
+	MaxFee      uint `json:"max_fee,omitempty"`
+	PriorityFee uint `json:"priority_fee,omitempty"`
+
+	// Optional window the TX can be mined in, as block heights or unix times (see LockTimeThreshold)
+	ValidAfter uint64 `json:"valid_after,omitempty"`
+	ValidUntil uint64 `json:"valid_until,omitempty"`
+
+	// Registers the multisig account at the To address, which must be derived from it
+	RegisterMultisig *MultisigAccount `json:"register_multisig,omitempty"`
+
+	// Batch TXs pay each output under a single nonce and signature
+	Outputs []TxOutput `json:"outputs,omitempty"`
+
+	// Most gas the TX may use when it deploys or calls a contract
+	GasLimit uint `json:"gas_limit,omitempty"`
+
+	Token *TokenOp `json:"token,omitempty"`
+	HTLC  *HTLCOp  `json:"htlc,omitempty"`
+	Name  *NameOp  `json:"name,omitempty"`
 }
 
 type SignedTx struct {
 	Tx
 	Sig []byte `json:"signature"`
+
+	// TXs from a multisig account carry the account and its signers' signatures instead of Sig
+	Multisig *MultisigAccount `json:"multisig,omitempty"`
+	Sigs     [][]byte         `json:"signatures,omitempty"`
 }
 
 func NewTx(from, to common.Address, value, nonce uint, data string) Tx {
-	return Tx{from, to, value, nonce, data, uint64(time.Now().Unix())}
+	return Tx{From: from, To: to, Value: value, Nonce: nonce, Data: data, Time: uint64(time.Now().Unix())}
 }
 
 func NewSignedTx(tx Tx, sig []byte) SignedTx {
-	return SignedTx{tx, sig}
+	return SignedTx{Tx: tx, Sig: sig}
 }
 
 func (t Tx) IsReward() bool {
 	return t.Data == "reward"
 }
 
+// valueRecipient is the account credited with the TX's Value: the new contract
+// of a deploy, and nobody for an HTLC lock whose value stays locked until claimed.
+// Batch TXs pay their Outputs instead, and no account is credited a zero Value,
+// so TXs without a recipient, like name registrations, don't create balances.
+func (t Tx) valueRecipient() (common.Address, bool) {
+	if t.IsHTLCLock() || t.IsBatch() || t.Value == 0 {
+		return common.Address{}, false
+	}
+
+	if t.IsContractDeploy() {
+		return t.ContractAddress(), true
+	}
+
+	return t.To, true
+}
+
+// validateSingleOp rejects TXs combining several operations, e.g. a token
+// transfer with an HTLC lock, as each one would apply its own rules to the same
+// sender, recipient and value.
+func validateSingleOp(tx SignedTx, s *State) error {
+	_, isCall := s.contracts[tx.To]
+
+	ops := []struct {
+		name string
+		set  bool
+	}{
+		{"token", tx.Token != nil},
+		{"HTLC", tx.HTLC != nil},
+		{"name", tx.Name != nil},
+		{"batch", tx.IsBatch()},
+		{"multisig registration", tx.RegisterMultisig != nil},
+		{"contract deploy", tx.IsContractDeploy()},
+		{"contract call", isCall},
+	}
+
+	var set []string
+	for _, op := range ops {
+		if op.set {
+			set = append(set, op.name)
+		}
+	}
+
+	if len(set) > 1 {
+		return fmt.Errorf("wrong TX. TX must perform a single operation, not %s", strings.Join(set, ", "))
+	}
+
+	return nil
+}
+
+// Cost is the most the TX can take from the sender's balance.
 func (t Tx) Cost() uint {
-	return t.Value + TxFee
+	return t.TotalValue() + t.FeeCap() + t.NameFee() + t.GasFeeCap()
+}
+
+// FeeCap is the most the TX pays in fees. TXs without an explicit fee pay the flat TxFee.
+func (t Tx) FeeCap() uint {
+	if t.MaxFee > 0 {
+		return t.MaxFee
+	}
+
+	if t.Fee == 0 {
+		return TxFee
+	}
+
+	return t.Fee
+}
+
+// MinerTip is the part of the fee paid to the miner on top of the burned base fee.
+// TXs without a MaxFee tip everything above the base fee.
+func (t Tx) MinerTip(baseFee uint) uint {
+	if t.FeeCap() < baseFee {
+		return 0
+	}
+
+	tip := t.FeeCap() - baseFee
+	if t.MaxFee > 0 && t.PriorityFee < tip {
+		tip = t.PriorityFee
+	}
+
+	return tip
+}
+
+// ChargedFee is the fee taken from the sender in a block with the given base fee.
+func (t Tx) ChargedFee(baseFee uint) uint {
+	return baseFee + t.MinerTip(baseFee)
 }
 
 func (t Tx) Hash() (Hash, error) {
@@ -59,67 +172,175 @@ func (t Tx) Encode() ([]byte, error) {
 
 func (t Tx) MarshalJSON() ([]byte, error) {
 	type legacyTx struct {
-		From  common.Address `json:"from"`
-		To    common.Address `json:"to"`
-		Value uint           `json:"value"`
-		Nonce uint           `json:"nonce"`
-		Data  string         `json:"data"`
-		Time  uint64         `json:"time"`
+		From        common.Address `json:"from"`
+		To          common.Address `json:"to"`
+		Value       uint           `json:"value"`
+		Fee         uint           `json:"fee,omitempty"`
+		Nonce       uint           `json:"nonce"`
+		Data        string         `json:"data"`
+		Time        uint64         `json:"time"`
+		MaxFee      uint           `json:"max_fee,omitempty"`
+		PriorityFee uint           `json:"priority_fee,omitempty"`
+		ValidAfter  uint64         `json:"valid_after,omitempty"`
+		ValidUntil  uint64         `json:"valid_until,omitempty"`
+
+		RegisterMultisig *MultisigAccount `json:"register_multisig,omitempty"`
+		Outputs          []TxOutput       `json:"outputs,omitempty"`
+		GasLimit         uint             `json:"gas_limit,omitempty"`
+		Token            *TokenOp         `json:"token,omitempty"`
+		HTLC             *HTLCOp          `json:"htlc,omitempty"`
+		Name             *NameOp          `json:"name,omitempty"`
 	}
 	return json.Marshal(legacyTx{
-		From:  t.From,
-		To:    t.To,
-		Value: t.Value,
-		Nonce: t.Nonce,
-		Data:  t.Data,
-		Time:  t.Time,
+		From:        t.From,
+		To:          t.To,
+		Value:       t.Value,
+		Fee:         t.Fee,
+		Nonce:       t.Nonce,
+		Data:        t.Data,
+		Time:        t.Time,
+		MaxFee:      t.MaxFee,
+		PriorityFee: t.PriorityFee,
+		ValidAfter:  t.ValidAfter,
+		ValidUntil:  t.ValidUntil,
+
+		RegisterMultisig: t.RegisterMultisig,
+		Outputs:          t.Outputs,
+		GasLimit:         t.GasLimit,
+		Token:            t.Token,
+		HTLC:             t.HTLC,
+		Name:             t.Name,
 	})
 }
 
 func (t SignedTx) MarshalJSON() ([]byte, error) {
 	type legacyTx struct {
-		From  common.Address `json:"from"`
-		To    common.Address `json:"to"`
-		Value uint           `json:"value"`
-		Nonce uint           `json:"nonce"`
-		Data  string         `json:"data"`
-		Time  uint64         `json:"time"`
-		Sig   []byte         `json:"signature"`
+		From        common.Address `json:"from"`
+		To          common.Address `json:"to"`
+		Value       uint           `json:"value"`
+		Fee         uint           `json:"fee,omitempty"`
+		Nonce       uint           `json:"nonce"`
+		Data        string         `json:"data"`
+		Time        uint64         `json:"time"`
+		MaxFee      uint           `json:"max_fee,omitempty"`
+		PriorityFee uint           `json:"priority_fee,omitempty"`
+		ValidAfter  uint64         `json:"valid_after,omitempty"`
+		ValidUntil  uint64         `json:"valid_until,omitempty"`
+
+		RegisterMultisig *MultisigAccount `json:"register_multisig,omitempty"`
+		Outputs          []TxOutput       `json:"outputs,omitempty"`
+		GasLimit         uint             `json:"gas_limit,omitempty"`
+		Token            *TokenOp         `json:"token,omitempty"`
+		HTLC             *HTLCOp          `json:"htlc,omitempty"`
+		Name             *NameOp          `json:"name,omitempty"`
+
+		Sig      []byte           `json:"signature"`
+		Multisig *MultisigAccount `json:"multisig,omitempty"`
+		Sigs     [][]byte         `json:"signatures,omitempty"`
 	}
 	return json.Marshal(legacyTx{
-		From:  t.From,
-		To:    t.To,
-		Value: t.Value,
-		Nonce: t.Nonce,
-		Data:  t.Data,
-		Time:  t.Time,
-		Sig:   t.Sig,
+		From:        t.From,
+		To:          t.To,
+		Value:       t.Value,
+		Fee:         t.Fee,
+		Nonce:       t.Nonce,
+		Data:        t.Data,
+		Time:        t.Time,
+		MaxFee:      t.MaxFee,
+		PriorityFee: t.PriorityFee,
+		ValidAfter:  t.ValidAfter,
+		ValidUntil:  t.ValidUntil,
+
+		RegisterMultisig: t.RegisterMultisig,
+		Outputs:          t.Outputs,
+		GasLimit:         t.GasLimit,
+		Token:            t.Token,
+		HTLC:             t.HTLC,
+		Name:             t.Name,
+
+		Sig:      t.Sig,
+		Multisig: t.Multisig,
+		Sigs:     t.Sigs,
 	})
 }
 
+// Hash identifies the signed TX by the unsigned TX, plus the account sending it
+// when it's a multisig one. Signatures are left out: any Threshold of the signers'
+// signatures authenticate a multisig TX, so they mustn't change its hash.
 func (t SignedTx) Hash() (Hash, error) {
-	txJson, err := t.Encode()
+	txJson, err := t.Tx.Encode()
 	if err != nil {
 		return Hash{}, err
 	}
 
+	if t.IsMultisig() {
+		multisigJson, err := json.Marshal(t.Multisig)
+		if err != nil {
+			return Hash{}, err
+		}
+		txJson = append(txJson, multisigJson...)
+	}
+
 	return sha256.Sum256(txJson), nil
 }
 
+// EncodeRaw is the canonical encoding of the signed TX, its JSON hex encoded
+// with a '0x' prefix, as submitted to a node's POST /tx/send-raw.
+func (t SignedTx) EncodeRaw() (string, error) {
+	txJson, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	return "0x" + hex.EncodeToString(txJson), nil
+}
+
+func DecodeRawTx(raw string) (SignedTx, error) {
+	if !strings.HasPrefix(raw, "0x") {
+		return SignedTx{}, fmt.Errorf("raw TX must be hex encoded with a '0x' prefix")
+	}
+
+	txJson, err := hex.DecodeString(raw[2:])
+	if err != nil {
+		return SignedTx{}, fmt.Errorf("raw TX is not hex encoded. %s", err.Error())
+	}
+
+	var tx SignedTx
+	err = json.Unmarshal(txJson, &tx)
+	if err != nil {
+		return SignedTx{}, fmt.Errorf("raw TX is not a JSON encoded signed TX. %s", err.Error())
+	}
+
+	return tx, nil
+}
+
 func (t SignedTx) IsAuthentic() (bool, error) {
+	if t.IsMultisig() {
+		return t.isMultisigAuthentic()
+	}
+
 	txHash, err := t.Tx.Hash()
 	if err != nil {
 		return false, err
 	}
 
-	recoveredPubKey, err := crypto.SigToPub(txHash[:], t.Sig)
+	recoveredAccount, err := recoverSigner(txHash, t.Sig)
 	if err != nil {
 		return false, err
 	}
 
+	return recoveredAccount.Hex() == t.From.Hex(), nil
+}
+
+// recoverSigner returns the account whose private key produced sig over hash.
+func recoverSigner(hash Hash, sig []byte) (common.Address, error) {
+	recoveredPubKey, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+
 	recoveredPubKeyBytes := elliptic.Marshal(crypto.S256(), recoveredPubKey.X, recoveredPubKey.Y)
 	recoveredPubKeyBytesHash := crypto.Keccak256(recoveredPubKeyBytes[1:])
-	recoveredAccount := common.BytesToAddress(recoveredPubKeyBytesHash[12:])
 
-	return recoveredAccount.Hex() == t.From.Hex(), nil
+	return common.BytesToAddress(recoveredPubKeyBytesHash[12:]), nil
 }
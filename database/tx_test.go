@@ -0,0 +1,61 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestTXsWithSeveralOpsAreRejected(t *testing.T) {
+	s, key := newTestState(t)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	recipient, contract := common.HexToAddress("0x11"), common.HexToAddress("0x44")
+	s.contracts[contract] = Contract{Storage: map[uint64]uint64{}}
+
+	tokenOp := &TokenOp{Type: TokenOpCreate, Symbol: "TOK", Amount: 100}
+	htlcOp := &HTLCOp{Type: HTLCOpLock, HashLock: Hash{1}, Timeout: 100}
+	nameOp := &NameOp{Type: NameOpRegister, Name: "alice"}
+	outputs := []TxOutput{{To: recipient, Value: 10}}
+	multisig := &MultisigAccount{Signers: []common.Address{sender}, Threshold: 1}
+
+	tests := []struct {
+		name   string
+		to     common.Address
+		data   string
+		modify func(tx *Tx)
+		valid  bool
+	}{
+		{"token op only", recipient, "", func(tx *Tx) { tx.Token = tokenOp }, true},
+		{"contract call only", contract, "", func(tx *Tx) {}, true},
+		{"token and HTLC", recipient, "", func(tx *Tx) { tx.Token, tx.HTLC = tokenOp, htlcOp }, false},
+		{"name and batch", recipient, "", func(tx *Tx) { tx.Name, tx.Outputs = nameOp, outputs }, false},
+		{"multisig registration and token", recipient, "", func(tx *Tx) { tx.RegisterMultisig, tx.Token = multisig, tokenOp }, false},
+		{"contract deploy and name", common.Address{}, "0x00", func(tx *Tx) { tx.Name = nameOp }, false},
+		{"contract call and HTLC", contract, "", func(tx *Tx) { tx.HTLC = htlcOp }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := NewTx(sender, test.to, 0, 1, test.data)
+			test.modify(&tx)
+
+			err := validateSingleOp(SignedTx{Tx: tx}, s)
+			if test.valid && err != nil {
+				t.Fatalf("expected a single op to be valid. %s", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expected a TX with several ops to be rejected")
+			}
+		})
+	}
+
+	tx := NewTx(sender, recipient, 0, 1, "")
+	tx.Token, tx.Name = tokenOp, nameOp
+
+	err := ValidateTx(signTestTx(t, tx, key), s)
+	if err == nil || !strings.Contains(err.Error(), "single operation") {
+		t.Fatalf("expected the TX with several ops to be rejected, got %v", err)
+	}
+}
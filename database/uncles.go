@@ -0,0 +1,88 @@
+package database
+
+import "fmt"
+
+const (
+	MaxUncles                   = 2
+	MaxUncleDepth               = 6
+	UncleRewardDenominator      = 8
+	UncleInclusionRewardDivisor = 32
+)
+
+// uncleReward shrinks the block reward by 1/UncleRewardDenominator for every block
+// the uncle is behind the block including it.
+func uncleReward(blockReward uint, depth uint64) uint {
+	return blockReward * uint(UncleRewardDenominator-depth) / UncleRewardDenominator
+}
+
+// ValidateUncle checks the side chain block header can be included as an uncle of
+// the block at the given height: it must be a recent sibling of a canonical block,
+// sealed with valid PoW and never included before. Only headers committing to their
+// payload can be verified without the uncle's TXs.
+func (s *State) ValidateUncle(uncle BlockHeader, number uint64) error {
+	if s.consensus.Name() != ConsensusPoW {
+		return fmt.Errorf("uncles are only allowed with PoW consensus")
+	}
+
+	if uncle.PayloadHash == nil {
+		return fmt.Errorf("uncle %d header doesn't commit to its payload", uncle.Number)
+	}
+
+	if len(uncle.Uncles) > 0 {
+		return fmt.Errorf("uncle %d can't include uncles itself", uncle.Number)
+	}
+
+	if uncle.Number >= number || number-uncle.Number > MaxUncleDepth {
+		return fmt.Errorf("uncle %d is not within %d blocks before block %d", uncle.Number, MaxUncleDepth, number)
+	}
+
+	uncleHash, err := uncle.Hash()
+	if err != nil {
+		return err
+	}
+
+	if uncleHash == s.blockHashes[uncle.Number] {
+		return fmt.Errorf("uncle %x is a canonical block", uncleHash)
+	}
+
+	expectedParent := Hash{}
+	if uncle.Number > 0 {
+		expectedParent = s.blockHashes[uncle.Number-1]
+	}
+
+	if uncle.Parent != expectedParent {
+		return fmt.Errorf("uncle %x parent must be the canonical block '%x'", uncleHash, expectedParent)
+	}
+
+	if _, ok := s.includedUncles[uncleHash]; ok {
+		return fmt.Errorf("uncle %x was already included", uncleHash)
+	}
+
+	return s.consensus.VerifySeal(Block{Header: uncle}, s)
+}
+
+func validateUncles(b Block, s *State) error {
+	if len(b.Header.Uncles) > MaxUncles {
+		return fmt.Errorf("block has %d uncles, the limit is %d", len(b.Header.Uncles), MaxUncles)
+	}
+
+	seen := make(map[Hash]bool)
+	for _, uncle := range b.Header.Uncles {
+		err := s.ValidateUncle(uncle, b.Header.Number)
+		if err != nil {
+			return err
+		}
+
+		uncleHash, err := uncle.Hash()
+		if err != nil {
+			return err
+		}
+
+		if seen[uncleHash] {
+			return fmt.Errorf("uncle %x is included twice", uncleHash)
+		}
+		seen[uncleHash] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestIncludeUncleHeader(t *testing.T) {
+	s, _ := newTestState(t)
+	miner, uncleMiner := common.HexToAddress("0x22"), common.HexToAddress("0x33")
+	now := uint64(time.Now().Unix())
+
+	canonical := mineTestBlock(t, NewBlock(Hash{}, 0, 0, now, miner, nil))
+	canonicalHash, err := s.AddBlock(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uncle := mineTestBlock(t, NewBlock(Hash{}, 0, 0, now+1, uncleMiner, nil))
+
+	// claim the uncle for another miner, skipping the rare accounts still sealing it
+	forged := uncle.Header
+	for i := int64(0x34); ; i++ {
+		forged.Miner = common.BigToAddress(big.NewInt(i))
+
+		forgedHash, err := forged.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !IsBlockHashValid(forgedHash, testMiningDifficulty) {
+			break
+		}
+	}
+
+	legacy := uncle.Header
+	legacy.PayloadHash = nil
+
+	tests := []struct {
+		name  string
+		uncle BlockHeader
+		valid bool
+	}{
+		{"sealed sibling", uncle.Header, true},
+		{"header not matching its seal", forged, false},
+		{"header without payload hash", legacy, false},
+		{"canonical block", canonical.Header, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.ValidateUncle(test.uncle, 1)
+			if test.valid && err != nil {
+				t.Fatalf("expected the uncle to be valid. %s", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expected the uncle to be rejected")
+			}
+		})
+	}
+
+	block := NewBlock(canonicalHash, 1, 0, now+2, miner, nil)
+	block.Header.Uncles = []BlockHeader{uncle.Header}
+
+	_, err = s.AddBlock(mineTestBlock(t, block))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[uncleMiner] != uncleReward(BlockReward, 1) || s.Balances[miner] != 2*BlockReward+BlockReward/UncleInclusionRewardDivisor {
+		t.Fatalf("expected both miners to be rewarded for the uncle, balances are %d and %d", s.Balances[uncleMiner], s.Balances[miner])
+	}
+
+	if s.ValidateUncle(uncle.Header, 2) == nil {
+		t.Fatal("expected an uncle to be included once only")
+	}
+
+	err = s.RemoveBlocks(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Balances[uncleMiner] != 0 || s.ValidateUncle(uncle.Header, 1) != nil {
+		t.Fatal("expected removing the block to revert the uncle inclusion")
+	}
+}
@@ -0,0 +1,308 @@
+package database
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Opcodes of the contract VM, a subset of the EVM's with the same numbering.
+// Words are 64-bit and arithmetic wraps around, so execution is deterministic.
+const (
+	OpStop         byte = 0x00
+	OpAdd          byte = 0x01
+	OpMul          byte = 0x02
+	OpSub          byte = 0x03
+	OpDiv          byte = 0x04
+	OpMod          byte = 0x06
+	OpLt           byte = 0x10
+	OpGt           byte = 0x11
+	OpEq           byte = 0x14
+	OpIsZero       byte = 0x15
+	OpAnd          byte = 0x16
+	OpOr           byte = 0x17
+	OpXor          byte = 0x18
+	OpNot          byte = 0x19
+	OpCallValue    byte = 0x34
+	OpCallDataLoad byte = 0x35
+	OpCallDataSize byte = 0x36
+	OpTimestamp    byte = 0x42
+	OpNumber       byte = 0x43
+	OpPop          byte = 0x50
+	OpSLoad        byte = 0x54
+	OpSStore       byte = 0x55
+	OpJump         byte = 0x56
+	OpJumpI        byte = 0x57
+	OpJumpDest     byte = 0x5b
+	OpPush1        byte = 0x60
+	OpPush8        byte = 0x67
+	OpDup1         byte = 0x80
+	OpDup16        byte = 0x8f
+	OpSwap1        byte = 0x90
+	OpSwap16       byte = 0x9f
+	OpLog1         byte = 0xa1
+	OpReturn       byte = 0xf3
+	OpRevert       byte = 0xfd
+)
+
+const (
+	GasDefault   = 3
+	GasJumpDest  = 1
+	GasSLoad     = 50
+	GasSStore    = 100
+	GasLog       = 20
+	GasCodeByte  = 10
+	MaxStackSize = 1024
+	MaxCodeSize  = 24576
+)
+
+// ExecContext is what a contract can read about the TX and block executing it.
+type ExecContext struct {
+	Value    uint
+	CallData []byte
+	Number   uint64
+	Time     uint64
+}
+
+// Host gives the executed contract access to its key-value storage and lets it emit logs.
+type Host interface {
+	Load(key uint64) uint64
+	Store(key, value uint64)
+	Log(topic, data uint64)
+}
+
+// Execute runs the contract code until it stops, returns or fails. Every opcode
+// costs gas and the execution fails once more than gasLimit is used.
+func Execute(code []byte, ctx ExecContext, host Host, gasLimit uint) (ret uint64, gasUsed uint, err error) {
+	stack := make([]uint64, 0, 16)
+	jumpDests := validJumpDests(code)
+
+	pop := func() (uint64, error) {
+		if len(stack) == 0 {
+			return 0, fmt.Errorf("stack underflow")
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		return v, nil
+	}
+
+	push := func(v uint64) error {
+		if len(stack) >= MaxStackSize {
+			return fmt.Errorf("stack overflow")
+		}
+		stack = append(stack, v)
+
+		return nil
+	}
+
+	pc := 0
+	for pc < len(code) {
+		op := code[pc]
+
+		gasUsed += opGas(op)
+		if gasUsed > gasLimit {
+			return 0, gasLimit, fmt.Errorf("out of gas")
+		}
+
+		switch {
+		case op == OpStop:
+			return 0, gasUsed, nil
+
+		case op >= OpPush1 && op <= OpPush8:
+			n := int(op-OpPush1) + 1
+			if pc+n >= len(code) {
+				return 0, gasUsed, fmt.Errorf("PUSH%d at %d reads past the code", n, pc)
+			}
+			err = push(readWord(code[pc+1 : pc+1+n]))
+			pc += n
+
+		case op >= OpDup1 && op <= OpDup16:
+			n := int(op-OpDup1) + 1
+			if len(stack) < n {
+				return 0, gasUsed, fmt.Errorf("stack underflow")
+			}
+			err = push(stack[len(stack)-n])
+
+		case op >= OpSwap1 && op <= OpSwap16:
+			n := int(op-OpSwap1) + 1
+			if len(stack) <= n {
+				return 0, gasUsed, fmt.Errorf("stack underflow")
+			}
+			top := len(stack) - 1
+			stack[top], stack[top-n] = stack[top-n], stack[top]
+
+		case op == OpJumpDest:
+
+		case op == OpJump || op == OpJumpI:
+			dest, cond := uint64(0), uint64(1)
+			dest, err = pop()
+			if err == nil && op == OpJumpI {
+				cond, err = pop()
+			}
+			if err != nil {
+				return 0, gasUsed, err
+			}
+
+			if cond != 0 {
+				if dest >= uint64(len(code)) || !jumpDests[dest] {
+					return 0, gasUsed, fmt.Errorf("invalid jump destination %d", dest)
+				}
+				pc = int(dest)
+				continue
+			}
+
+		case op == OpReturn || op == OpRevert:
+			v, err := pop()
+			if err != nil {
+				return 0, gasUsed, err
+			}
+
+			if op == OpRevert {
+				return v, gasUsed, fmt.Errorf("execution reverted with %d", v)
+			}
+
+			return v, gasUsed, nil
+
+		default:
+			err = execOp(op, ctx, host, pop, push)
+		}
+
+		if err != nil {
+			return 0, gasUsed, err
+		}
+
+		pc++
+	}
+
+	return 0, gasUsed, nil
+}
+
+func execOp(op byte, ctx ExecContext, host Host, pop func() (uint64, error), push func(uint64) error) error {
+	switch op {
+	case OpCallValue:
+		return push(uint64(ctx.Value))
+	case OpCallDataSize:
+		return push(uint64(len(ctx.CallData)))
+	case OpTimestamp:
+		return push(ctx.Time)
+	case OpNumber:
+		return push(ctx.Number)
+	}
+
+	a, err := pop()
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case OpPop:
+		return nil
+	case OpIsZero:
+		return push(boolWord(a == 0))
+	case OpNot:
+		return push(^a)
+	case OpCallDataLoad:
+		word := make([]byte, 8)
+		if a < uint64(len(ctx.CallData)) {
+			copy(word, ctx.CallData[a:])
+		}
+		return push(binary.BigEndian.Uint64(word))
+	case OpSLoad:
+		return push(host.Load(a))
+	}
+
+	b, err := pop()
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case OpAdd:
+		return push(a + b)
+	case OpMul:
+		return push(a * b)
+	case OpSub:
+		return push(a - b)
+	case OpDiv:
+		if b == 0 {
+			return push(0)
+		}
+		return push(a / b)
+	case OpMod:
+		if b == 0 {
+			return push(0)
+		}
+		return push(a % b)
+	case OpLt:
+		return push(boolWord(a < b))
+	case OpGt:
+		return push(boolWord(a > b))
+	case OpEq:
+		return push(boolWord(a == b))
+	case OpAnd:
+		return push(a & b)
+	case OpOr:
+		return push(a | b)
+	case OpXor:
+		return push(a ^ b)
+	case OpSStore:
+		host.Store(a, b)
+		return nil
+	case OpLog1:
+		host.Log(a, b)
+		return nil
+	}
+
+	return fmt.Errorf("invalid opcode 0x%x", op)
+}
+
+func opGas(op byte) uint {
+	switch op {
+	case OpStop:
+		return 0
+	case OpJumpDest:
+		return GasJumpDest
+	case OpSLoad:
+		return GasSLoad
+	case OpSStore:
+		return GasSStore
+	case OpLog1:
+		return GasLog
+	}
+
+	return GasDefault
+}
+
+// validJumpDests marks the JUMPDESTs that aren't part of a PUSH's immediate data.
+func validJumpDests(code []byte) map[uint64]bool {
+	dests := make(map[uint64]bool)
+
+	for pc := 0; pc < len(code); pc++ {
+		op := code[pc]
+		if op == OpJumpDest {
+			dests[uint64(pc)] = true
+		}
+
+		if op >= OpPush1 && op <= OpPush8 {
+			pc += int(op-OpPush1) + 1
+		}
+	}
+
+	return dests
+}
+
+// readWord reads a big-endian PUSH immediate of up to 8 bytes.
+func readWord(b []byte) uint64 {
+	word := make([]byte, 8)
+	copy(word[8-len(b):], b)
+
+	return binary.BigEndian.Uint64(word)
+}
+
+func boolWord(b bool) uint64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
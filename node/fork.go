@@ -53,7 +53,8 @@ func (n *Node) doCheckForkedState() {
 			continue
 		}
 
-		if status.Number < n.state.LatestBlock().Header.Number {
+		// the heaviest chain wins, which is the longest one under PoW
+		if status.TotalDifficulty < n.state.TotalDifficulty() {
 			continue
 		}
 
@@ -64,6 +65,12 @@ func (n *Node) doCheckForkedState() {
 			continue
 		}
 
+		err = n.state.ValidateCheckpoints(peerBlocks)
+		if err != nil {
+			n.alertRefusedFork(peer, err)
+			continue
+		}
+
 		// Step 3: find forked blocks
 		forkedBlock, err := n.state.GetForkedBlock(peerBlocks)
 		if err != nil {
@@ -71,7 +78,22 @@ func (n *Node) doCheckForkedState() {
 			continue
 		}
 
+		err = n.state.ValidateReorg(forkedBlock)
+		if err != nil {
+			n.alertRefusedFork(peer, err)
+			continue
+		}
+
+		n.pendingBlockMu.Lock()
+		n.txPoolMu.Lock()
+		err = n.rememberUncleCandidates(forkedBlock)
+		if err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+		}
+
 		err = n.state.RemoveBlocks(forkedBlock)
+		n.txPoolMu.Unlock()
+		n.pendingBlockMu.Unlock()
 		if err != nil {
 			fmt.Printf("ERROR: %s\n", err)
 			continue
@@ -100,3 +122,16 @@ func (n *Node) getBlocksFromPeer(peer PeerNode) ([]database.Block, error) {
 
 	return blocks, nil
 }
+
+// alertRefusedFork reports a peer offering a chain we refuse to switch to, as it
+// conflicts with our checkpoints or reorganizes too many blocks, in the logs and
+// in the node status.
+func (n *Node) alertRefusedFork(peer PeerNode, err error) {
+	alert := fmt.Sprintf("%s: refused the chain of peer '%s'. %s", time.Now().UTC().Format(time.RFC3339), peer.TcpAddress(), err.Error())
+	fmt.Printf("ALERT: %s\n", alert)
+
+	n.forkAlerts = append(n.forkAlerts, alert)
+	if len(n.forkAlerts) > maxForkAlerts {
+		n.forkAlerts = n.forkAlerts[len(n.forkAlerts)-maxForkAlerts:]
+	}
+}
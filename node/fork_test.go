@@ -0,0 +1,98 @@
+package node
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+func TestRefusedDeepReorgIsAlerted(t *testing.T) {
+	acc := common.HexToAddress("0x22")
+
+	dataDir := t.TempDir()
+	genesis := fmt.Sprintf(`{"symbol": "ETH", "max_reorg_depth": 1, "balances": {"%s": 1000000}}`, acc.Hex())
+	err := database.InitDataDirIfNotExists(dataDir, []byte(genesis))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := loadTestNode(t, dataDir, acc)
+
+	mineChildBlock := func(parent database.Block, number uint64, blockTime uint64, miner common.Address) database.Block {
+		parentHash := database.Hash{}
+		if number > 0 {
+			parentHash, err = parent.Hash()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		pb := NewPendingBlock(parentHash, number, miner, nil)
+		pb.Time = blockTime
+
+		return mineTestBlock(t, pb)
+	}
+
+	// the peer's chain forks after block 0 and is heavier, but switching to it removes 2 blocks
+	now := uint64(time.Now().Unix())
+	block0 := mineChildBlock(database.Block{}, 0, now-100, acc)
+	block1 := mineChildBlock(block0, 1, now-90, acc)
+	block2 := mineChildBlock(block1, 2, now-80, acc)
+	for _, block := range []database.Block{block0, block1, block2} {
+		_, err = n.state.AddBlock(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	latestHash := n.state.LatestBlockHash()
+
+	peerMiner := common.HexToAddress("0x33")
+	peerBlock1 := mineChildBlock(block0, 1, now-85, peerMiner)
+	peerBlock2 := mineChildBlock(peerBlock1, 2, now-75, peerMiner)
+	peerBlock3 := mineChildBlock(peerBlock2, 3, now-70, peerMiner)
+	peerBlocks := []database.Block{block0, peerBlock1, peerBlock2, peerBlock3}
+
+	peerHash, err := peerBlock3.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(endpointStatus, func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, StatusResponse{Hash: peerHash, Number: 3, TotalDifficulty: 4})
+	})
+	mux.HandleFunc(endpointListBlocks, func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, peerBlocks)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerPort, err := strconv.ParseUint(serverURL.Port(), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := PeerNode{IP: serverURL.Hostname(), Port: peerPort}
+	n.knownPeers = map[string]PeerNode{peer.TcpAddress(): peer}
+
+	n.doCheckForkedState()
+
+	if n.state.LatestBlockHash() != latestHash {
+		t.Fatal("expected the reorg deeper than the max reorg depth to be refused")
+	}
+
+	if len(n.forkAlerts) != 1 || !strings.Contains(n.forkAlerts[0], peer.TcpAddress()) {
+		t.Fatalf("expected the refused reorg to be alerted, alerts are %v", n.forkAlerts)
+	}
+}
@@ -2,6 +2,7 @@ package node
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,7 +10,14 @@ import (
 )
 
 func writeErrorResponse(w http.ResponseWriter, err error) {
-	errorJson, _ := json.Marshal(ErrorResponse{err.Error()})
+	res := ErrorResponse{Error: err.Error()}
+
+	var txPoolErr *TxPoolError
+	if errors.As(err, &txPoolErr) {
+		res.Code = txPoolErr.Code
+	}
+
+	errorJson, _ := json.Marshal(res)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusInternalServerError)
 	_, err = w.Write(errorJson)
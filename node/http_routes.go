@@ -14,6 +14,8 @@ import (
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Why the txpool rejected the TX, see the TxPoolErr codes
+	Code string `json:"code,omitempty"`
 }
 
 type BalancesResponse struct {
@@ -26,7 +28,61 @@ type AddTxRequest struct {
 	FromPwd string `json:"from_pwd"`
 	To      string `json:"to"`
 	Value   uint   `json:"value"`
+	Fee     uint   `json:"fee"`
 	Data    string `json:"data"`
+
+	// Fee market TXs set MaxFee and the PriorityFee tipped to the miner instead of Fee
+	MaxFee      uint `json:"max_fee"`
+	PriorityFee uint `json:"priority_fee"`
+
+	// Optional block height or unix time window the TX can be mined in
+	ValidAfter uint64 `json:"valid_after"`
+	ValidUntil uint64 `json:"valid_until"`
+
+	// Gas the TX may use to deploy (To empty, Data "0x" code) or call a contract
+	GasLimit uint `json:"gas_limit"`
+
+	// Creates, transfers to To or burns a native token
+	Token *database.TokenOp `json:"token"`
+
+	// Locks Value for To in a hash-time-locked escrow, or claims or refunds one
+	HTLC *database.HTLCOp `json:"htlc"`
+
+	// Registers, renews or transfers a name. 'from' and 'to' also accept registered names
+	Name *database.NameOp `json:"name"`
+
+	// Registers the multisig account, sending Value to its address
+	RegisterMultisig *database.MultisigAccount `json:"register_multisig"`
+
+	// Nonce of a pooled TX to replace with a higher fee, the next nonce when empty
+	Nonce *uint `json:"nonce"`
+}
+
+type AddBatchTxRequest struct {
+	From    string                 `json:"from"`
+	FromPwd string                 `json:"from_pwd"`
+	Outputs []BatchTxOutputRequest `json:"outputs"`
+	Fee     uint                   `json:"fee"`
+
+	MaxFee      uint `json:"max_fee"`
+	PriorityFee uint `json:"priority_fee"`
+}
+
+// BatchTxOutputRequest pays Value to To, an address or a registered name.
+type BatchTxOutputRequest struct {
+	To    string `json:"to"`
+	Value uint   `json:"value"`
+}
+
+// SendRawTxRequest carries a TX signed by the client, either as JSON in Tx or
+// in its canonical '0x' hex encoding in Raw.
+type SendRawTxRequest struct {
+	Raw string             `json:"raw"`
+	Tx  *database.SignedTx `json:"tx"`
+}
+
+type SendRawTxResponse struct {
+	Hash database.Hash `json:"hash"`
 }
 
 type AddWalletRequest struct {
@@ -49,11 +105,14 @@ type CreateWalletResponse struct {
 }
 
 type StatusResponse struct {
-	Hash       database.Hash       `json:"block_hash"`
-	Number     uint64              `json:"block_number"`
-	KnownPeers map[string]PeerNode `json:"peers_known"`
-	PendingTXs []database.SignedTx `json:"pending_txs"`
-	Account    common.Address      `json:"account"`
+	Hash            database.Hash       `json:"block_hash"`
+	Number          uint64              `json:"block_number"`
+	TotalDifficulty uint64              `json:"total_difficulty"`
+	KnownPeers      map[string]PeerNode `json:"peers_known"`
+	PendingTXs      []database.SignedTx `json:"pending_txs"`
+	Account         common.Address      `json:"account"`
+
+	ForkAlerts []string `json:"fork_alerts,omitempty"`
 }
 
 type NodeInfo struct {
@@ -67,6 +126,47 @@ type SyncResponse struct {
 	Blocks []database.Block `json:"blocks"`
 }
 
+type SubmitWorkRequest struct {
+	WorkHash database.Hash `json:"work_hash"`
+	Nonce    uint32        `json:"nonce"`
+}
+
+type SubmitWorkResponse struct {
+	Hash database.Hash `json:"block_hash"`
+}
+
+type SealersResponse struct {
+	Sealers   []common.Address        `json:"sealers"`
+	Proposals map[common.Address]bool `json:"proposals"`
+}
+
+type ProposeSealerRequest struct {
+	Candidate string `json:"candidate"`
+	Authorize bool   `json:"authorize"`
+}
+
+type AccountBalanceResponse struct {
+	Hash    database.Hash  `json:"block_hash"`
+	Account common.Address `json:"account"`
+	Balance uint           `json:"balance"`
+}
+
+type TokensResponse struct {
+	Hash   database.Hash    `json:"block_hash"`
+	Tokens []database.Token `json:"tokens"`
+}
+
+type AccountTokensResponse struct {
+	Hash     database.Hash   `json:"block_hash"`
+	Account  common.Address  `json:"account"`
+	Balances map[string]uint `json:"balances"`
+}
+
+type MempoolResponse struct {
+	Pending map[common.Address][]database.SignedTx `json:"pending"`
+	Queued  map[common.Address][]database.SignedTx `json:"queued"`
+}
+
 type AddPeerResponse struct {
 	Success bool   `json:"success"`
 	Error   string `json:"error"`
@@ -76,6 +176,23 @@ func listBalancesHandler(w http.ResponseWriter, state *database.State) {
 	writeResponse(w, BalancesResponse{state.LatestBlockHash(), state.Balances})
 }
 
+// accountBalanceHandler serves GET /balances/{address or name}.
+func accountBalanceHandler(w http.ResponseWriter, r *http.Request, state *database.State) {
+	param := strings.TrimPrefix(r.URL.Path, endpointBalances)
+	if param == "" {
+		writeErrorResponse(w, fmt.Errorf("expected %s{address}", endpointBalances))
+		return
+	}
+
+	acc, err := state.ResolveAccount(param)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, AccountBalanceResponse{state.LatestBlockHash(), acc, state.Balances[acc]})
+}
+
 func createWallet(w http.ResponseWriter, r *http.Request, node *Node) {
 	req := CreateWalletRequest{}
 	err := readRequest(r, &req)
@@ -88,7 +205,7 @@ func createWallet(w http.ResponseWriter, r *http.Request, node *Node) {
 		writeErrorResponse(w, errors.New("password is required"))
 		return
 	}
-	acc, err := wallet.NewKeystoreAccount(req.Password)
+	acc, err := wallet.NewKeystoreAccount(req.Password, node.keystoreDir)
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -104,7 +221,78 @@ func addTxHandler(w http.ResponseWriter, r *http.Request, node *Node) {
 		return
 	}
 
-	from := database.NewAccount(req.From)
+	from, err := node.state.ResolveAccount(req.From)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	if from.String() == common.HexToAddress("").String() {
+		writeErrorResponse(w, fmt.Errorf("%s is an invalid 'from' sender", from.String()))
+		return
+	}
+
+	if req.FromPwd == "" {
+		writeErrorResponse(w, fmt.Errorf("password to decrypt the %s account is required. 'from_pwd' is empty", from.String()))
+		return
+	}
+
+	to, err := node.state.ResolveAccount(req.To)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	if req.RegisterMultisig != nil {
+		to = req.RegisterMultisig.Address()
+	}
+
+	nonce := node.NextPendingNonce(from)
+	if req.Nonce != nil {
+		nonce = *req.Nonce
+	}
+
+	tx := database.NewTx(from, to, req.Value, nonce, req.Data)
+	tx.Fee = req.Fee
+	tx.MaxFee = req.MaxFee
+	tx.PriorityFee = req.PriorityFee
+	tx.ValidAfter = req.ValidAfter
+	tx.ValidUntil = req.ValidUntil
+	tx.RegisterMultisig = req.RegisterMultisig
+	tx.GasLimit = req.GasLimit
+	tx.Token = req.Token
+	tx.HTLC = req.HTLC
+	tx.Name = req.Name
+
+	signedTx, err := wallet.SignTxWithKeystoreAccount(tx, from, req.FromPwd, node.keystoreDir)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	err = node.AddPendingTX(signedTx, node.info)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, AddTxResponse{Success: true})
+}
+
+// addBatchTxHandler pays all the outputs in a single TX.
+func addBatchTxHandler(w http.ResponseWriter, r *http.Request, node *Node) {
+	req := AddBatchTxRequest{}
+	err := readRequest(r, &req)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	from, err := node.state.ResolveAccount(req.From)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
 
 	if from.String() == common.HexToAddress("").String() {
 		writeErrorResponse(w, fmt.Errorf("%s is an invalid 'from' sender", from.String()))
@@ -116,10 +304,35 @@ func addTxHandler(w http.ResponseWriter, r *http.Request, node *Node) {
 		return
 	}
 
-	nonce := node.state.GetNextAccountNonce(from)
-	tx := database.NewTx(from, database.NewAccount(req.To), req.Value, nonce, req.Data)
+	if len(req.Outputs) == 0 {
+		writeErrorResponse(w, errors.New("batch TX requires at least one output"))
+		return
+	}
+
+	outputs := make([]database.TxOutput, len(req.Outputs))
+	for i, out := range req.Outputs {
+		if out.To == "" {
+			writeErrorResponse(w, fmt.Errorf("batch output %d requires a 'to' recipient", i))
+			return
+		}
+
+		to, err := node.state.ResolveAccount(out.To)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+
+		outputs[i] = database.TxOutput{To: to, Value: out.Value}
+	}
+
+	nonce := node.NextPendingNonce(from)
+	tx := database.NewTx(from, common.Address{}, 0, nonce, "")
+	tx.Outputs = outputs
+	tx.Fee = req.Fee
+	tx.MaxFee = req.MaxFee
+	tx.PriorityFee = req.PriorityFee
 
-	signedTx, err := wallet.SignTxWithKeystoreAccount(tx, from, req.FromPwd, wallet.GetKeystoreDirPath())
+	signedTx, err := wallet.SignTxWithKeystoreAccount(tx, from, req.FromPwd, node.keystoreDir)
 	if err != nil {
 		writeErrorResponse(w, err)
 		return
@@ -134,6 +347,70 @@ func addTxHandler(w http.ResponseWriter, r *http.Request, node *Node) {
 	writeResponse(w, AddTxResponse{Success: true})
 }
 
+// addMultisigTxHandler accepts a TX from a multisig account once its signers
+// collected enough signatures offline.
+func addMultisigTxHandler(w http.ResponseWriter, r *http.Request, node *Node) {
+	tx := database.SignedTx{}
+	err := readRequest(r, &tx)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	if !tx.IsMultisig() {
+		writeErrorResponse(w, fmt.Errorf("TX is not sent from a multisig account"))
+		return
+	}
+
+	err = node.AddPendingTX(tx, node.info)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, AddTxResponse{Success: true})
+}
+
+// sendRawTxHandler accepts a TX signed offline, so the sender's keys never reach the node.
+func sendRawTxHandler(w http.ResponseWriter, r *http.Request, node *Node) {
+	req := SendRawTxRequest{}
+	err := readRequest(r, &req)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	if (req.Raw == "") == (req.Tx == nil) {
+		writeErrorResponse(w, fmt.Errorf("either the 'raw' or the 'tx' signed TX is required"))
+		return
+	}
+
+	var tx database.SignedTx
+	if req.Tx != nil {
+		tx = *req.Tx
+	} else {
+		tx, err = database.DecodeRawTx(req.Raw)
+		if err != nil {
+			writeErrorResponse(w, err)
+			return
+		}
+	}
+
+	err = node.AddPendingTX(tx, node.info)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	txHash, err := tx.Hash()
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, SendRawTxResponse{Hash: txHash})
+}
+
 func addWalletHandler(w http.ResponseWriter, r *http.Request, node *Node) {
 	req := AddWalletRequest{}
 	err := readRequest(r, &req)
@@ -147,7 +424,7 @@ func addWalletHandler(w http.ResponseWriter, r *http.Request, node *Node) {
 		return
 	}
 
-	acc, err := wallet.NewKeystoreAccount(req.Password)
+	acc, err := wallet.NewKeystoreAccount(req.Password, node.keystoreDir)
 	if err != nil {
 		writeErrorResponse(w, err)
 		return
@@ -172,7 +449,7 @@ func nodeInfoHandler(w http.ResponseWriter, node *Node) {
 		Nodes:        peers,
 		PendingTXs:   node.getPendingTXsAsArray(),
 		Blocks:       blocks,
-		PendingBlock: node.pendingBlock,
+		PendingBlock: node.PendingBlock(),
 	}
 
 	writeResponse(w, res)
@@ -190,11 +467,14 @@ func listBlocksHandler(w http.ResponseWriter, state *database.State) {
 
 func statusHandler(w http.ResponseWriter, node *Node) {
 	res := StatusResponse{
-		Hash:       node.state.LatestBlockHash(),
-		Number:     node.state.LatestBlock().Header.Number,
-		KnownPeers: node.knownPeers,
-		PendingTXs: node.getPendingTXsAsArray(),
-		Account:    database.NewAccount(node.info.Account.String()),
+		Hash:            node.state.LatestBlockHash(),
+		Number:          node.state.LatestBlock().Header.Number,
+		TotalDifficulty: node.state.TotalDifficulty(),
+		KnownPeers:      node.knownPeers,
+		PendingTXs:      node.getPendingTXsAsArray(),
+		Account:         database.NewAccount(node.info.Account.String()),
+
+		ForkAlerts: node.forkAlerts,
 	}
 
 	writeResponse(w, res)
@@ -268,6 +548,168 @@ func blockByNumberOrHash(w http.ResponseWriter, r *http.Request, node *Node) {
 	writeResponse(w, block)
 }
 
-func mempoolViewer(w http.ResponseWriter, txs map[string]database.SignedTx) {
-	writeResponse(w, txs)
+// txReceiptHandler serves GET /tx/{hash}/receipt.
+func txReceiptHandler(w http.ResponseWriter, r *http.Request, state *database.State) {
+	params := strings.Split(strings.TrimPrefix(r.URL.Path, endpointTx), "/")
+	if len(params) != 2 || params[1] != endpointTxReceipt {
+		writeErrorResponse(w, fmt.Errorf("expected %s{hash}/%s", endpointTx, endpointTxReceipt))
+		return
+	}
+
+	var txHash database.Hash
+	err := txHash.UnmarshalText([]byte(params[0]))
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	receipt, ok := state.Receipt(txHash)
+	if !ok {
+		writeErrorResponse(w, fmt.Errorf("receipt of TX %s not found", txHash.Hex()))
+		return
+	}
+
+	writeResponse(w, receipt)
+}
+
+// htlcHandler serves GET /htlc/{id}, the escrow locked by the TX with the id hash.
+func htlcHandler(w http.ResponseWriter, r *http.Request, state *database.State) {
+	var id database.Hash
+	err := id.UnmarshalText([]byte(strings.TrimPrefix(r.URL.Path, endpointHTLC)))
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	htlc, ok := state.HTLC(id)
+	if !ok {
+		writeErrorResponse(w, fmt.Errorf("HTLC %s not found", id.Hex()))
+		return
+	}
+
+	writeResponse(w, htlc)
+}
+
+func tokensHandler(w http.ResponseWriter, state *database.State) {
+	writeResponse(w, TokensResponse{state.LatestBlockHash(), state.Tokens()})
+}
+
+// accountTokensHandler serves GET /account/{address or name}/tokens.
+func accountTokensHandler(w http.ResponseWriter, r *http.Request, state *database.State) {
+	params := strings.Split(strings.TrimPrefix(r.URL.Path, endpointAccount), "/")
+	if len(params) != 2 || params[1] != endpointAccountTokens || params[0] == "" {
+		writeErrorResponse(w, fmt.Errorf("expected %s{address}/%s", endpointAccount, endpointAccountTokens))
+		return
+	}
+
+	acc, err := state.ResolveAccount(params[0])
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, AccountTokensResponse{state.LatestBlockHash(), acc, state.TokenBalances(acc)})
+}
+
+func mempoolViewer(w http.ResponseWriter, node *Node) {
+	node.txPoolMu.Lock()
+	res := MempoolResponse{Pending: node.txPool.PendingBySender(), Queued: node.txPool.QueuedBySender()}
+	node.txPoolMu.Unlock()
+
+	writeResponse(w, res)
+}
+
+func getWorkHandler(w http.ResponseWriter, node *Node) {
+	work, err := node.GetWork()
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, work)
+}
+
+func submitWorkHandler(w http.ResponseWriter, r *http.Request, node *Node) {
+	req := SubmitWorkRequest{}
+	err := readRequest(r, &req)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	blockHash, err := node.SubmitWork(req.WorkHash, req.Nonce)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, SubmitWorkResponse{blockHash})
+}
+
+func poolStatsHandler(w http.ResponseWriter, node *Node) {
+	if node.stratum == nil {
+		writeErrorResponse(w, errors.New("stratum server is not enabled"))
+		return
+	}
+
+	writeResponse(w, node.stratum.WorkerStats())
+}
+
+func sealersHandler(w http.ResponseWriter, node *Node) {
+	poa, ok := node.state.Consensus().(*database.PoA)
+	if !ok {
+		writeErrorResponse(w, errors.New("sealers are only available with PoA consensus"))
+		return
+	}
+
+	writeResponse(w, SealersResponse{poa.Sealers(), node.sealerProposals})
+}
+
+func proposeSealerHandler(w http.ResponseWriter, r *http.Request, node *Node) {
+	req := ProposeSealerRequest{}
+	err := readRequest(r, &req)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	err = node.ProposeSealer(database.NewAccount(req.Candidate), req.Authorize)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, AddTxResponse{Success: true})
+}
+
+func supplyHandler(w http.ResponseWriter, r *http.Request, state *database.State) {
+	heightRaw := r.URL.Query().Get(endpointChainSupplyQueryKeyHeight)
+	if heightRaw == "" {
+		writeResponse(w, state.LatestSupply())
+		return
+	}
+
+	height, err := strconv.ParseUint(heightRaw, 10, 64)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	supply, err := state.SupplyAt(height)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, supply)
+}
+
+func feesEstimateHandler(w http.ResponseWriter, state *database.State) {
+	estimate, err := state.EstimateFee(feeEstimateBlocks)
+	if err != nil {
+		writeErrorResponse(w, err)
+		return
+	}
+
+	writeResponse(w, estimate)
 }
@@ -0,0 +1,79 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
+)
+
+func TestSendRawTx(t *testing.T) {
+	n, key := newTestNode(t)
+	recipient := common.HexToAddress("0x11")
+
+	sendRawTx := func(req SendRawTxRequest) *httptest.ResponseRecorder {
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		sendRawTxHandler(w, httptest.NewRequest(http.MethodPost, endpointTxSendRaw, bytes.NewReader(body)), n)
+
+		return w
+	}
+
+	raw, err := wallet.SignRawTx(database.NewTx(n.info.Account, recipient, 10, 1, ""), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := sendRawTx(SendRawTxRequest{Raw: raw})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the raw TX to be accepted, got %s", w.Body.String())
+	}
+
+	res := SendRawTxResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := n.txPool.Get(res.Hash.Hex()); !ok {
+		t.Fatalf("expected the TX %s to be pending", res.Hash.Hex())
+	}
+
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := signTestTx(t, database.NewTx(n.info.Account, recipient, 10, 2, ""), otherKey)
+
+	tests := []struct {
+		name string
+		req  SendRawTxRequest
+	}{
+		{"forged sender", SendRawTxRequest{Tx: &forged}},
+		{"malformed encoding", SendRawTxRequest{Raw: "0xzz"}},
+		{"both encodings", SendRawTxRequest{Raw: raw, Tx: &forged}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := sendRawTx(test.req)
+			if w.Code == http.StatusOK {
+				t.Fatal("expected the TX to be rejected")
+			}
+
+			if n.txPool.PendingCount() != 1 {
+				t.Fatalf("expected only the first TX to be pending, %d TXs pending", n.txPool.PendingCount())
+			}
+		})
+	}
+}
@@ -2,24 +2,50 @@ package node
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ngoduongkha/go-ethereum-cloner/database"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
 )
 
 type PendingBlock struct {
-	Parent database.Hash       `json:"parent"`
-	Number uint64              `json:"number"`
-	Time   uint64              `json:"time"`
-	Miner  common.Address      `json:"miner"`
-	TXs    []database.SignedTx `json:"txs"`
+	Parent  database.Hash          `json:"parent"`
+	Number  uint64                 `json:"number"`
+	Time    uint64                 `json:"time"`
+	Miner   common.Address         `json:"miner"`
+	BaseFee uint                   `json:"base_fee,omitempty"`
+	Uncles  []database.BlockHeader `json:"uncles,omitempty"`
+	Vote    *database.SealerVote   `json:"vote,omitempty"`
+	TXs     []database.SignedTx    `json:"txs"`
+	GasUsed uint                   `json:"gas_used,omitempty"`
+
+	// PoA only: whether the node seals the block in turn
+	Difficulty uint `json:"difficulty,omitempty"`
 }
 
 func NewPendingBlock(parent database.Hash, number uint64, miner common.Address, txs []database.SignedTx) PendingBlock {
-	return PendingBlock{parent, number, uint64(time.Now().Unix()), miner, txs}
+	return PendingBlock{Parent: parent, Number: number, Time: uint64(time.Now().Unix()), Miner: miner, TXs: txs}
+}
+
+// Hash identifies the PendingBlock regardless of the nonce it will be sealed with.
+func (pb PendingBlock) Hash() (database.Hash, error) {
+	return pb.Block(0).Hash()
+}
+
+// Block builds the block to be sealed out of the PendingBlock and a PoW nonce.
+func (pb PendingBlock) Block(nonce uint32) database.Block {
+	block := database.NewBlock(pb.Parent, pb.Number, nonce, pb.Time, pb.Miner, pb.TXs)
+	block.Header.BaseFee = pb.BaseFee
+	block.Header.Uncles = pb.Uncles
+	block.Header.Vote = pb.Vote
+	block.Header.GasUsed = pb.GasUsed
+	block.Header.Difficulty = pb.Difficulty
+
+	return block
 }
 
 func Mine(ctx context.Context, pb PendingBlock, miningDifficulty uint) (database.Block, error) {
@@ -49,7 +75,7 @@ func Mine(ctx context.Context, pb PendingBlock, miningDifficulty uint) (database
 			fmt.Printf("Mining %d Pending TXs. Attempt: %d\n", len(pb.TXs), attempt)
 		}
 
-		block = database.NewBlock(pb.Parent, pb.Number, nonce, pb.Time, pb.Miner, pb.TXs)
+		block = pb.Block(nonce)
 		blockHash, err := block.Hash()
 		if err != nil {
 			return database.Block{}, fmt.Errorf("couldn't mine block. %s", err.Error())
@@ -71,6 +97,35 @@ func Mine(ctx context.Context, pb PendingBlock, miningDifficulty uint) (database
 	return block, nil
 }
 
+// Seal signs the PendingBlock with the sealer's key under PoA consensus.
+func Seal(pb PendingBlock, sealerKey *ecdsa.PrivateKey) (database.Block, error) {
+	if len(pb.TXs) == 0 {
+		return database.Block{}, fmt.Errorf("sealing empty blocks is not allowed")
+	}
+
+	if sealerKey == nil {
+		return database.Block{}, fmt.Errorf("sealer key is required to seal PoA blocks")
+	}
+
+	block, err := wallet.SealBlock(pb.Block(0), sealerKey)
+	if err != nil {
+		return database.Block{}, fmt.Errorf("couldn't seal block. %s", err.Error())
+	}
+
+	hash, err := block.Hash()
+	if err != nil {
+		return database.Block{}, err
+	}
+
+	fmt.Printf("\nSealed new Block '%x' using PoA 🎉🎉🎉\n", hash)
+	fmt.Printf("\tHeight: '%v'\n", block.Header.Number)
+	fmt.Printf("\tCreated: '%v'\n", block.Header.Time)
+	fmt.Printf("\tSealer: '%v'\n", block.Header.Miner.String())
+	fmt.Printf("\tParent: '%v'\n\n", block.Header.Parent.Hex())
+
+	return block, nil
+}
+
 func generateNonce() uint32 {
 	rand.Seed(time.Now().UTC().UnixNano())
 
@@ -1,14 +1,20 @@
 package node
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ngoduongkha/go-ethereum-cloner/database"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
 	"github.com/rs/cors"
 )
 
@@ -41,10 +47,47 @@ const (
 	endpointMempoolViewer       = "/mempool/"
 )
 
+const (
+	endpointMiningGetWork    = "/mining/work"
+	endpointMiningSubmitWork = "/mining/submit"
+	endpointMiningPoolStats  = "/mining/pool"
+)
+
+const (
+	endpointChainSupply               = "/chain/supply"
+	endpointChainSupplyQueryKeyHeight = "height"
+)
+
+const endpointFeesEstimate = "/fees/estimate"
+
+const (
+	endpointTx        = "/tx/"
+	endpointTxReceipt = "receipt"
+	endpointTxSendRaw = "/tx/send-raw"
+)
+
+const endpointHTLC = "/htlc/"
+
+const endpointBalances = "/balances/"
+
+const (
+	endpointTokens        = "/tokens"
+	endpointAccount       = "/account/"
+	endpointAccountTokens = "tokens"
+)
+
+const (
+	endpointConsensusSealers = "/consensus/sealers"
+	endpointConsensusPropose = "/consensus/propose"
+)
+
 const (
 	miningIntervalSeconds           = 10
 	syncIntervalSeconds             = 15
 	checkForkedStateIntervalSeconds = 30
+	txJournalRotateIntervalSeconds  = 3600
+	feeEstimateBlocks               = 20
+	maxForkAlerts                   = 10
 	DefaultMiningDifficulty         = 3
 )
 
@@ -70,40 +113,87 @@ type Node struct {
 	dataDir string
 	info    PeerNode
 
+	// Keystore the HTTP API creates accounts in and unlocks them from
+	keystoreDir string
+
 	// The main blockchain state after all TXs from mined blocks were applied
 	state *database.State
 
 	// temporary pending state validating new incoming TXs but reset after the block is mined
 	pendingState *database.State
 
-	knownPeers      map[string]PeerNode
-	pendingTXs      map[string]database.SignedTx
-	archivedTXs     map[string]database.SignedTx
+	knownPeers  map[string]PeerNode
+	txPool      *TxPool
+	archivedTXs map[string]database.SignedTx
+	// TXs not valid before a later block height or time, added to the txPool once they are
+	timeLockedTXs   map[string]database.SignedTx
 	newSyncedBlocks chan database.Block
 	newPendingTXs   chan database.SignedTx
-	pendingBlock    PendingBlock
+
+	// Guards the mempool: txPool, pendingState, archivedTXs, timeLockedTXs, the TX
+	// journal and the uncle candidates, plus the state while adding a block. Never
+	// lock pendingBlockMu while holding it.
+	txPoolMu sync.Mutex
+
+	// Signalled whenever the work handed out to external miners may have changed
+	workChanged chan struct{}
+
+	// Block being sealed by the internal miner or handed out to external miners.
+	// The mutex also serializes adding blocks with building the next pending one.
+	pendingBlock   PendingBlock
+	pendingBlockMu sync.Mutex
 
 	// Number of zeroes the hash must start with to be considered valid. Default 3
 	miningDifficulty uint
 	isMining         bool
+
+	// When disabled, blocks are only sealed by external miners via the getwork/submitwork API
+	isInternalMinerEnabled bool
+
+	// Seal a block as soon as a TX enters the mempool. Used by the --dev mode
+	isInstantSeal bool
+
+	// Optional Stratum-like TCP server sharing the PendingBlock between pool miners
+	stratum *StratumServer
+
+	// PoA only: key signing the sealed blocks and sealer votes to cast
+	sealerKey       *ecdsa.PrivateKey
+	sealerProposals map[common.Address]bool
+
+	// Recently seen side chain blocks the miner may reward as uncles
+	uncleCandidates map[database.Hash]database.BlockHeader
+
+	// Latest peers offering chains conflicting with the checkpoints or too deep a reorg
+	forkAlerts []string
+
+	// Mempool TXs persisted in the data dir, reloaded on restart
+	txJournal *txJournal
 }
 
 func New(dataDir string, ip string, port uint64, acc common.Address, bootstrap PeerNode, miningDifficulty uint) *Node {
 	knownPeers := make(map[string]PeerNode)
 
 	n := &Node{
-		dataDir:          dataDir,
-		info:             NewPeerNode(ip, port, false, acc, true),
-		knownPeers:       knownPeers,
-		pendingTXs:       make(map[string]database.SignedTx),
-		archivedTXs:      make(map[string]database.SignedTx),
-		newSyncedBlocks:  make(chan database.Block),
-		newPendingTXs:    make(chan database.SignedTx, 10000),
-		isMining:         false,
-		miningDifficulty: miningDifficulty,
+		dataDir:                dataDir,
+		keystoreDir:            wallet.GetKeystoreDirPath(),
+		info:                   NewPeerNode(ip, port, false, acc, true),
+		knownPeers:             knownPeers,
+		txPool:                 NewTxPool(DefaultTxPoolConfig()),
+		archivedTXs:            make(map[string]database.SignedTx),
+		timeLockedTXs:          make(map[string]database.SignedTx),
+		newSyncedBlocks:        make(chan database.Block),
+		newPendingTXs:          make(chan database.SignedTx, 10000),
+		workChanged:            make(chan struct{}, 1),
+		isMining:               false,
+		miningDifficulty:       miningDifficulty,
+		isInternalMinerEnabled: true,
+		sealerProposals:        make(map[common.Address]bool),
+		uncleCandidates:        make(map[database.Hash]database.BlockHeader),
 	}
 
-	n.AddPeer(bootstrap)
+	if bootstrap.IP != "" {
+		n.AddPeer(bootstrap)
+	}
 
 	return n
 }
@@ -131,6 +221,20 @@ func (n *Node) Run(ctx context.Context) error {
 	pendingState := state.Copy()
 	n.pendingState = &pendingState
 
+	err = n.loadTxJournal()
+	if err != nil {
+		return err
+	}
+	defer func(journal *txJournal) {
+		n.txPoolMu.Lock()
+		defer n.txPoolMu.Unlock()
+
+		err := journal.close()
+		if err != nil {
+			fmt.Println("Error closing TX journal:", err)
+		}
+	}(n.txJournal)
+
 	fmt.Println("Blockchain state:")
 	fmt.Printf("	- height: %d\n", n.state.LatestBlock().Header.Number)
 	fmt.Printf("	- hash: %s\n", n.state.LatestBlockHash().Hex())
@@ -154,6 +258,15 @@ func (n *Node) Run(ctx context.Context) error {
 		}
 	}()
 
+	if n.stratum != nil {
+		go func() {
+			err := n.stratum.Run(ctx)
+			if err != nil {
+				fmt.Println("Error running stratum server:", err)
+			}
+		}()
+	}
+
 	return n.serveHttp(ctx)
 }
 
@@ -169,6 +282,10 @@ func (n *Node) serveHttp(ctx context.Context) error {
 		listBalancesHandler(w, n.state)
 	})
 
+	mux.HandleFunc(endpointBalances, func(w http.ResponseWriter, r *http.Request) {
+		accountBalanceHandler(w, r, n.state)
+	})
+
 	mux.HandleFunc("/wallet/create", func(w http.ResponseWriter, r *http.Request) {
 		createWallet(w, r, n)
 	})
@@ -177,10 +294,38 @@ func (n *Node) serveHttp(ctx context.Context) error {
 		addTxHandler(w, r, n)
 	})
 
+	mux.HandleFunc("/tx/batch", func(w http.ResponseWriter, r *http.Request) {
+		addBatchTxHandler(w, r, n)
+	})
+
+	mux.HandleFunc("/tx/add-multisig", func(w http.ResponseWriter, r *http.Request) {
+		addMultisigTxHandler(w, r, n)
+	})
+
+	mux.HandleFunc(endpointTxSendRaw, func(w http.ResponseWriter, r *http.Request) {
+		sendRawTxHandler(w, r, n)
+	})
+
+	mux.HandleFunc(endpointTx, func(w http.ResponseWriter, r *http.Request) {
+		txReceiptHandler(w, r, n.state)
+	})
+
+	mux.HandleFunc(endpointHTLC, func(w http.ResponseWriter, r *http.Request) {
+		htlcHandler(w, r, n.state)
+	})
+
 	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
 		addWalletHandler(w, r, n)
 	})
 
+	mux.HandleFunc(endpointTokens, func(w http.ResponseWriter, r *http.Request) {
+		tokensHandler(w, n.state)
+	})
+
+	mux.HandleFunc(endpointAccount, func(w http.ResponseWriter, r *http.Request) {
+		accountTokensHandler(w, r, n.state)
+	})
+
 	mux.HandleFunc(endpointNodeInfo, func(w http.ResponseWriter, r *http.Request) {
 		nodeInfoHandler(w, n)
 	})
@@ -206,7 +351,35 @@ func (n *Node) serveHttp(ctx context.Context) error {
 	})
 
 	mux.HandleFunc(endpointMempoolViewer, func(w http.ResponseWriter, r *http.Request) {
-		mempoolViewer(w, n.pendingTXs)
+		mempoolViewer(w, n)
+	})
+
+	mux.HandleFunc(endpointMiningGetWork, func(w http.ResponseWriter, r *http.Request) {
+		getWorkHandler(w, n)
+	})
+
+	mux.HandleFunc(endpointMiningSubmitWork, func(w http.ResponseWriter, r *http.Request) {
+		submitWorkHandler(w, r, n)
+	})
+
+	mux.HandleFunc(endpointMiningPoolStats, func(w http.ResponseWriter, r *http.Request) {
+		poolStatsHandler(w, n)
+	})
+
+	mux.HandleFunc(endpointChainSupply, func(w http.ResponseWriter, r *http.Request) {
+		supplyHandler(w, r, n.state)
+	})
+
+	mux.HandleFunc(endpointFeesEstimate, func(w http.ResponseWriter, r *http.Request) {
+		feesEstimateHandler(w, n.state)
+	})
+
+	mux.HandleFunc(endpointConsensusSealers, func(w http.ResponseWriter, r *http.Request) {
+		sealersHandler(w, n)
+	})
+
+	mux.HandleFunc(endpointConsensusPropose, func(w http.ResponseWriter, r *http.Request) {
+		proposeSealerHandler(w, r, n)
 	})
 
 	handler := cors.AllowAll().Handler(mux)
@@ -229,23 +402,45 @@ func (n *Node) mine(ctx context.Context) error {
 	var stopCurrentMining context.CancelFunc
 
 	ticker := time.NewTicker(time.Second * miningIntervalSeconds)
+	journalTicker := time.NewTicker(time.Second * txJournalRotateIntervalSeconds)
+
+	minePendingTXsIfIdle := func() {
+		if n.isInternalMinerEnabled && n.pendingTXsCount() > 0 && !n.isMining {
+			n.isMining = true
+
+			miningCtx, stopCurrentMining = context.WithCancel(ctx)
+			err := n.sealPendingTXs(miningCtx)
+			if err != nil {
+				fmt.Printf("ERROR: %s\n", err)
+			}
+
+			n.isMining = false
+		}
+	}
+
+	// In instant seal mode every new pending TX is sealed right away instead of waiting for the ticker
+	var instantSealTXs chan database.SignedTx
+	if n.isInstantSeal {
+		instantSealTXs = n.newPendingTXs
+	}
 
 	for {
 		select {
 		case <-ticker.C:
-			go func() {
-				if len(n.pendingTXs) > 0 && !n.isMining {
-					n.isMining = true
-
-					miningCtx, stopCurrentMining = context.WithCancel(ctx)
-					err := n.minePendingTXs(miningCtx)
-					if err != nil {
-						fmt.Printf("ERROR: %s\n", err)
-					}
-
-					n.isMining = false
-				}
-			}()
+			n.txPoolMu.Lock()
+			n.evictStaleTXs()
+			n.updateTimeLockedTXs()
+			n.txPoolMu.Unlock()
+			go minePendingTXsIfIdle()
+
+		case <-instantSealTXs:
+			minePendingTXsIfIdle()
+
+		case <-journalTicker.C:
+			err := n.rotateTxJournal()
+			if err != nil {
+				fmt.Printf("ERROR: rotating TX journal: %s\n", err)
+			}
 
 		case block := <-n.newSyncedBlocks:
 			if n.isMining {
@@ -253,33 +448,37 @@ func (n *Node) mine(ctx context.Context) error {
 				fmt.Printf("\nPeer mined next Block '%s' faster :(\n", blockHash.Hex())
 
 				n.removeMinedPendingTXs(block)
-				n.pendingBlock = PendingBlock{}
+				n.setPendingBlock(PendingBlock{})
 				stopCurrentMining()
 			}
 
 		case <-ctx.Done():
 			ticker.Stop()
+			journalTicker.Stop()
 			return nil
 		}
 	}
 }
 
 func (n *Node) minePendingTXs(ctx context.Context) error {
-	blockToMine := NewPendingBlock(
-		n.state.LatestBlockHash(),
-		n.state.NextBlockNumber(),
-		n.info.Account,
-		n.getPendingTXsAsArray(),
-	)
+	if !n.canSealNextBlock() {
+		return nil
+	}
 
+	n.pendingBlockMu.Lock()
+	blockToMine := n.newPendingBlock()
 	n.pendingBlock = blockToMine
+	n.pendingBlockMu.Unlock()
+	n.notifyWorkChanged()
 
-	minedBlock, err := Mine(ctx, blockToMine, n.miningDifficulty)
+	minedBlock, err := n.seal(ctx, blockToMine)
 	if err != nil {
 		return err
 	}
 
-	n.removeMinedPendingTXs(minedBlock)
+	n.pendingBlockMu.Lock()
+	defer n.pendingBlockMu.Unlock()
+
 	n.pendingBlock = PendingBlock{}
 
 	err = n.addBlock(minedBlock)
@@ -287,23 +486,216 @@ func (n *Node) minePendingTXs(ctx context.Context) error {
 		return err
 	}
 
+	n.removeMinedPendingTXs(minedBlock)
+
 	return nil
 }
 
+// sealPendingTXs mines the pending TXs. In instant seal mode it keeps sealing
+// blocks until the mempool is empty, as the TXs added while a block is mined, or
+// not fitting in it, would otherwise wait for the mining ticker.
+func (n *Node) sealPendingTXs(ctx context.Context) error {
+	for {
+		err := n.minePendingTXs(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !n.isInstantSeal || n.pendingTXsCount() == 0 || !n.canSealNextBlock() {
+			return nil
+		}
+	}
+}
+
+func (n *Node) setPendingBlock(pb PendingBlock) {
+	n.pendingBlockMu.Lock()
+	defer n.pendingBlockMu.Unlock()
+
+	n.pendingBlock = pb
+	n.notifyWorkChanged()
+}
+
+// PendingBlock returns the block currently being sealed, empty when there is none.
+func (n *Node) PendingBlock() PendingBlock {
+	n.pendingBlockMu.Lock()
+	defer n.pendingBlockMu.Unlock()
+
+	return n.pendingBlock
+}
+
+// newPendingBlock prepares the next block out of the pending TXs, making sure
+// its time is past the chain's median time so it won't be rejected when blocks
+// are sealed faster than once per second.
+func (n *Node) newPendingBlock() PendingBlock {
+	n.txPoolMu.Lock()
+	defer n.txPoolMu.Unlock()
+
+	pb := NewPendingBlock(
+		n.state.LatestBlockHash(),
+		n.state.NextBlockNumber(),
+		n.info.Account,
+		[]database.SignedTx{},
+	)
+	pb.Vote = n.nextSealerVote()
+	pb.Difficulty = n.sealDifficulty(pb.Number)
+	pb.BaseFee = n.state.NextBaseFee()
+	pb.Uncles = n.selectUncles(pb.Number)
+
+	if medianTimePast := n.state.MedianTimePast(); pb.Time <= medianTimePast {
+		pb.Time = medianTimePast + 1
+	}
+
+	pb.TXs, pb.GasUsed = n.state.ApplicableTXs(n.selectPendingTXs(pb), pb.Time)
+
+	return pb
+}
+
+// selectPendingTXs picks the pending TXs tipping the miner the most per byte that
+// fit the block size and TX count limits. Each sender's TXs are taken in nonce order
+// and once one doesn't fit, its later TXs are skipped too so the block never has
+// a nonce gap.
+func (n *Node) selectPendingTXs(pb PendingBlock) []database.SignedTx {
+	// size the header with the largest nonce, a PoA seal, whichever engine seals it, and
+	// the largest gas used, which is only known once the selected TXs are applied
+	emptyBlock := pb.Block(math.MaxUint32)
+	emptyBlock.Header.Seal = make([]byte, crypto.SignatureLength)
+	emptyBlock.Header.GasUsed = database.MaxBlockGas
+	blockSize, err := emptyBlock.Size()
+	if err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		return []database.SignedTx{}
+	}
+
+	senderTXs := n.txPool.PendingBySender()
+
+	selected := make([]database.SignedTx, 0)
+	gasLimit := uint(0)
+
+	for uint(len(selected)) < n.state.MaxBlockTXs() {
+		var best common.Address
+		var bestSize uint
+		found := false
+
+		// pick the sender whose next TX pays the most per byte
+		for sender, txs := range senderTXs {
+			txSize, err := database.TxSize(txs[0])
+			if err != nil {
+				fmt.Printf("ERROR: %s\n", err)
+				delete(senderTXs, sender)
+				continue
+			}
+
+			if blockSize+txSize > n.state.MaxBlockBytes() || txs[0].FeeCap() < pb.BaseFee ||
+				gasLimit+txs[0].GasLimit > database.MaxBlockGas ||
+				txs[0].IsPremature(pb.Number, pb.Time) || txs[0].IsExpired(pb.Number, pb.Time) {
+				delete(senderTXs, sender)
+				continue
+			}
+
+			if !found || isHigherTipPerByte(txs[0], txSize, senderTXs[best][0], bestSize, pb.BaseFee) {
+				best, bestSize, found = sender, txSize, true
+			}
+		}
+
+		if !found {
+			break
+		}
+
+		selected = append(selected, senderTXs[best][0])
+		blockSize += bestSize
+		gasLimit += senderTXs[best][0].GasLimit
+
+		senderTXs[best] = senderTXs[best][1:]
+		if len(senderTXs[best]) == 0 {
+			delete(senderTXs, best)
+		}
+	}
+
+	return selected
+}
+
+// isHigherTipPerByte compares the miner tip per byte of a and b, breaking ties by
+// time and hash so every node builds the same order.
+func isHigherTipPerByte(a database.SignedTx, aSize uint, b database.SignedTx, bSize uint, baseFee uint) bool {
+	aFee, bFee := a.MinerTip(baseFee)*bSize, b.MinerTip(baseFee)*aSize
+	if aFee != bFee {
+		return aFee > bFee
+	}
+
+	if a.Time != b.Time {
+		return a.Time < b.Time
+	}
+
+	aHash, _ := a.Hash()
+	bHash, _ := b.Hash()
+
+	return bytes.Compare(aHash[:], bHash[:]) < 0
+}
+
+// seal turns the PendingBlock into a valid block according to the chain's consensus engine.
+func (n *Node) seal(ctx context.Context, pb PendingBlock) (database.Block, error) {
+	if _, ok := n.state.Consensus().(*database.PoA); ok {
+		return Seal(pb, n.sealerKey)
+	}
+
+	return Mine(ctx, pb, n.miningDifficulty)
+}
+
+// removeMinedPendingTXs archives the TXs of a block added to the chain. Adding
+// the block already dropped them from the pool as their nonces are used.
 func (n *Node) removeMinedPendingTXs(block database.Block) {
-	if len(block.TXs) > 0 && len(n.pendingTXs) > 0 {
+	n.txPoolMu.Lock()
+	defer n.txPoolMu.Unlock()
+
+	if len(block.TXs) > 0 {
 		fmt.Println("Updating in-memory Pending TXs Pool:")
 	}
 
 	for _, tx := range block.TXs {
 		txHash, _ := tx.Hash()
-		if _, exists := n.pendingTXs[txHash.Hex()]; exists {
-			fmt.Printf("\t-archiving mined TX: %s\n", txHash.Hex())
+		fmt.Printf("\t-archiving mined TX: %s\n", txHash.Hex())
 
-			n.archivedTXs[txHash.Hex()] = tx
-			delete(n.pendingTXs, txHash.Hex())
-		}
+		n.archivedTXs[txHash.Hex()] = tx
+		n.txPool.remove(tx)
+	}
+}
+
+// DisableInternalMiner stops the node from sealing blocks itself, leaving the PendingBlock to external miners.
+func (n *Node) DisableInternalMiner() {
+	n.isInternalMinerEnabled = false
+}
+
+// SetKeystoreDir changes the keystore the HTTP API uses, e.g. to a throwaway dev keystore.
+func (n *Node) SetKeystoreDir(dir string) {
+	n.keystoreDir = dir
+}
+
+// EnableInstantSeal makes the node seal a new block whenever a TX is added to the mempool.
+func (n *Node) EnableInstantSeal() {
+	n.isInstantSeal = true
+}
+
+// SetTxPoolConfig bounds the txpool, the zero fields keeping their defaults.
+func (n *Node) SetTxPoolConfig(config TxPoolConfig) {
+	if config.GlobalSlots == 0 {
+		config.GlobalSlots = DefaultTxPoolGlobalSlots
+	}
+	if config.AccountSlots == 0 {
+		config.AccountSlots = DefaultTxPoolAccountSlots
+	}
+	if config.Lifetime == 0 {
+		config.Lifetime = DefaultTxPoolLifetime
 	}
+	if config.PriceBump == 0 {
+		config.PriceBump = DefaultTxPoolPriceBump
+	}
+
+	n.txPool.config = config
+}
+
+// EnableStratum serves mining jobs to pool miners over TCP on the given port once the node runs.
+func (n *Node) EnableStratum(port uint64, shareDifficulty uint) {
+	n.stratum = NewStratumServer(n, port, shareDifficulty)
 }
 
 func (n *Node) ChangeMiningDifficulty(newDifficulty uint) {
@@ -330,6 +722,9 @@ func (n *Node) IsKnownPeer(peer PeerNode) bool {
 }
 
 func (n *Node) AddPendingTX(tx database.SignedTx, fromPeer PeerNode) error {
+	n.txPoolMu.Lock()
+	defer n.txPoolMu.Unlock()
+
 	txHash, err := tx.Hash()
 	if err != nil {
 		return err
@@ -340,32 +735,76 @@ func (n *Node) AddPendingTX(tx database.SignedTx, fromPeer PeerNode) error {
 		return err
 	}
 
-	_, isAlreadyPending := n.pendingTXs[txHash.Hex()]
+	_, isAlreadyPending := n.txPool.Get(txHash.Hex())
 	_, isArchived := n.archivedTXs[txHash.Hex()]
+	_, isTimeLocked := n.timeLockedTXs[txHash.Hex()]
+
+	if !isAlreadyPending && !isArchived && !isTimeLocked {
+		if tx.IsPremature(n.state.NextBlockNumber(), uint64(time.Now().Unix())) {
+			err = n.addTimeLockedTX(tx, txHash, fromPeer)
+			if err != nil {
+				return err
+			}
+
+			n.journalTX(tx)
+			return nil
+		}
 
-	if !isAlreadyPending && !isArchived {
-		err = n.validateTxBeforeAddingToMempool(tx)
+		promoted, err := n.addTxToPool(tx)
 		if err != nil {
 			return err
 		}
+		n.journalTX(tx)
+
+		if len(promoted) == 0 {
+			fmt.Printf("Queued TX %s from Peer %s until the sender's earlier nonces arrive\n", txJson, fromPeer.TcpAddress())
+			return nil
+		}
 
 		fmt.Printf("Added Pending TX %s from Peer %s\n", txJson, fromPeer.TcpAddress())
-		n.pendingTXs[txHash.Hex()] = tx
-		n.newPendingTXs <- tx
+		for _, tx := range promoted {
+			n.notifyPendingTX(tx)
+		}
 	}
 
 	return nil
 }
 
+// notifyPendingTX wakes up the instant sealing miner. Nobody reads the channel
+// otherwise, so the TX is dropped instead of blocking once its buffer is full.
+func (n *Node) notifyPendingTX(tx database.SignedTx) {
+	select {
+	case n.newPendingTXs <- tx:
+	default:
+	}
+
+	n.notifyWorkChanged()
+}
+
+// notifyWorkChanged wakes up the stratum server. A pending signal already covers
+// any later change, so the signal is dropped instead of blocking.
+func (n *Node) notifyWorkChanged() {
+	select {
+	case n.workChanged <- struct{}{}:
+	default:
+	}
+}
+
 func (n *Node) addBlock(block database.Block) error {
+	n.txPoolMu.Lock()
+	defer n.txPoolMu.Unlock()
+
 	_, err := n.state.AddBlock(block)
 	if err != nil {
 		return err
 	}
 
-	// Reset the pending state
-	pendingState := n.state.Copy()
-	n.pendingState = &pendingState
+	// Reset the pending state and re-validate the pooled TXs on top of the new block
+	n.revalidateTxPool()
+
+	n.updateTimeLockedTXs()
+
+	n.notifyWorkChanged()
 
 	return nil
 }
@@ -377,15 +816,25 @@ func (n *Node) validateTxBeforeAddingToMempool(tx database.SignedTx) error {
 }
 
 func (n *Node) getPendingTXsAsArray() []database.SignedTx {
-	txs := make([]database.SignedTx, len(n.pendingTXs))
+	n.txPoolMu.Lock()
+	defer n.txPoolMu.Unlock()
 
-	i := 0
-	for _, tx := range n.pendingTXs {
-		txs[i] = tx
-		i += 1
-	}
+	return n.txPool.Pending()
+}
+
+func (n *Node) pendingTXsCount() int {
+	n.txPoolMu.Lock()
+	defer n.txPoolMu.Unlock()
+
+	return n.txPool.PendingCount()
+}
+
+// NextPendingNonce is the account's next nonce after its pending TXs.
+func (n *Node) NextPendingNonce(acc common.Address) uint {
+	n.txPoolMu.Lock()
+	defer n.txPoolMu.Unlock()
 
-	return txs
+	return n.pendingState.GetNextAccountNonce(acc)
 }
 
 // Get known peers as an array
@@ -0,0 +1,96 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+func TestInstantSealMinesAllPendingTXs(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc := crypto.PubkeyToAddress(key.PublicKey)
+
+	// a single TX fits in a block, leaving the second one for the next block
+	dataDir := t.TempDir()
+	genesis := fmt.Sprintf(`{"symbol": "ETH", "max_block_txs": 1, "balances": {"%s": 1000000}}`, acc.Hex())
+	err = database.InitDataDirIfNotExists(dataDir, []byte(genesis))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := loadTestNode(t, dataDir, acc)
+	n.EnableInstantSeal()
+
+	for nonce := uint(1); nonce <= 2; nonce++ {
+		tx := database.NewTx(acc, common.HexToAddress("0x11"), 10, nonce, "")
+		err = n.AddPendingTX(signTestTx(t, tx, key), n.info)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = n.sealPendingTXs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.state.NextBlockNumber() != 2 || n.pendingTXsCount() != 0 {
+		t.Fatalf("expected both TXs to be sealed in 2 blocks, next block is %d with %d TXs pending", n.state.NextBlockNumber(), n.pendingTXsCount())
+	}
+}
+
+func TestPendingBlockPrefersHigherFees(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	balances := make([]string, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+		balances[i] = fmt.Sprintf(`"%s": 1000000`, crypto.PubkeyToAddress(key.PublicKey).Hex())
+	}
+
+	// only 2 of the 3 TXs fit in a block
+	dataDir := t.TempDir()
+	genesis := fmt.Sprintf(`{"symbol": "ETH", "max_block_txs": 2, "balances": {%s}}`, strings.Join(balances, ", "))
+	err := database.InitDataDirIfNotExists(dataDir, []byte(genesis))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := loadTestNode(t, dataDir, common.HexToAddress("0x22"))
+	recipient := common.HexToAddress("0x11")
+
+	underpaid := database.NewTx(crypto.PubkeyToAddress(keys[0].PublicKey), recipient, 10, 1, "")
+	underpaid.Fee = database.TxFee - 1
+	err = n.AddPendingTX(signTestTx(t, underpaid, keys[0]), n.info)
+	if err == nil {
+		t.Fatal("expected a TX paying less than the min fee to be rejected")
+	}
+
+	fees := []uint{database.TxFee, 3 * database.TxFee, 2 * database.TxFee}
+	for i, key := range keys {
+		tx := database.NewTx(crypto.PubkeyToAddress(key.PublicKey), recipient, 10, 1, "")
+		tx.Fee = fees[i]
+
+		err = n.AddPendingTX(signTestTx(t, tx, key), n.info)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pb := n.newPendingBlock()
+	if len(pb.TXs) != 2 || pb.TXs[0].Fee != fees[1] || pb.TXs[1].Fee != fees[2] {
+		t.Fatalf("expected the 2 highest paying TXs, highest first, got %+v", pb.TXs)
+	}
+}
@@ -0,0 +1,77 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+// SetSealerKey configures the private key of the node's account used to sign PoA blocks.
+func (n *Node) SetSealerKey(key *ecdsa.PrivateKey) {
+	n.sealerKey = key
+}
+
+// ProposeSealer makes the node vote to authorize (or deauthorize) the candidate in the blocks it seals.
+func (n *Node) ProposeSealer(candidate common.Address, authorize bool) error {
+	if _, ok := n.state.Consensus().(*database.PoA); !ok {
+		return fmt.Errorf("sealer voting requires PoA consensus")
+	}
+
+	n.sealerProposals[candidate] = authorize
+
+	return nil
+}
+
+// canSealNextBlock tells if the node may seal the next block: in its turn, or
+// out of turn once the in-turn sealer had PoAOutOfTurnDelay seconds to seal it.
+// Under PoW every node may always try.
+func (n *Node) canSealNextBlock() bool {
+	poa, ok := n.state.Consensus().(*database.PoA)
+	if !ok {
+		return true
+	}
+
+	next := n.state.NextBlockNumber()
+	if !poa.IsSealer(n.info.Account) || poa.SignedRecently(n.info.Account, next) {
+		return false
+	}
+
+	if poa.InTurnSealer(next) == n.info.Account || next == 0 {
+		return true
+	}
+
+	return uint64(time.Now().Unix()) >= n.state.LatestBlock().Header.Time+database.PoAOutOfTurnDelay
+}
+
+// sealDifficulty is the difficulty of the block the node seals at the given height, none under PoW.
+func (n *Node) sealDifficulty(number uint64) uint {
+	poa, ok := n.state.Consensus().(*database.PoA)
+	if !ok {
+		return 0
+	}
+
+	return poa.Difficulty(number, n.info.Account)
+}
+
+// nextSealerVote picks one of the node's pending sealer proposals, discarding
+// the ones that already took effect or would drop the last sealer.
+func (n *Node) nextSealerVote() *database.SealerVote {
+	poa, ok := n.state.Consensus().(*database.PoA)
+	if !ok {
+		return nil
+	}
+
+	for candidate, authorize := range n.sealerProposals {
+		if authorize == poa.IsSealer(candidate) || (!authorize && len(poa.Sealers()) == 1) {
+			delete(n.sealerProposals, candidate)
+			continue
+		}
+
+		return &database.SealerVote{Candidate: candidate, Authorize: authorize}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
+)
+
+func TestPoASealsOutOfTurnForOfflineSealer(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+
+	// sealers take turns in address order
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := crypto.PubkeyToAddress(keys[i].PublicKey), crypto.PubkeyToAddress(keys[j].PublicKey)
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+	sealers := make([]common.Address, len(keys))
+	for i, key := range keys {
+		sealers[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+
+	dataDir := t.TempDir()
+	genesis := fmt.Sprintf(
+		`{"symbol": "ETH", "balances": {"%s": 1000000}, "consensus": {"engine": "poa", "sealers": ["%s", "%s", "%s"]}}`,
+		sealers[2].Hex(), sealers[0].Hex(), sealers[1].Hex(), sealers[2].Hex(),
+	)
+	err := database.InitDataDirIfNotExists(dataDir, []byte(genesis))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := loadTestNode(t, dataDir, sealers[2])
+	n.SetSealerKey(keys[2])
+
+	sealTestBlock := func(sealer int, blockTime uint64, difficulty uint) database.Block {
+		pb := NewPendingBlock(n.state.LatestBlockHash(), n.state.NextBlockNumber(), sealers[sealer], nil)
+		pb.Time, pb.Difficulty, pb.BaseFee = blockTime, difficulty, n.state.NextBaseFee()
+
+		block, err := wallet.SealBlock(pb.Block(0), keys[sealer])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return block
+	}
+
+	// block 0 was sealed in turn a while ago, then the sealer of block 1 went offline
+	now := uint64(time.Now().Unix())
+	_, err = n.state.AddBlock(sealTestBlock(0, now-2*database.PoAOutOfTurnDelay, database.PoADifficultyInTurn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !n.canSealNextBlock() {
+		t.Fatal("expected to seal block 1 out of turn once its sealer is late")
+	}
+
+	tx := database.NewTx(sealers[2], common.HexToAddress("0x11"), 10, 1, "")
+	err = n.AddPendingTX(signTestTx(t, tx, keys[2]), n.info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.minePendingTXs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed := n.state.LatestBlock()
+	if sealed.Header.Number != 1 || sealed.Header.Miner != sealers[2] || sealed.Header.Difficulty != database.PoADifficultyOutOfTurn {
+		t.Fatalf("expected block 1 to be sealed out of turn, latest block is %+v", sealed.Header)
+	}
+
+	if n.canSealNextBlock() {
+		t.Fatal("expected the sealer to skip its turn right after sealing block 1")
+	}
+
+	parentTime := sealed.Header.Time
+	tests := []struct {
+		name       string
+		sealer     int
+		time       uint64
+		difficulty uint
+		valid      bool
+	}{
+		{"sealer of the previous block", 2, parentTime + database.PoAOutOfTurnDelay, database.PoADifficultyInTurn, false},
+		{"out of turn before the delay", 0, parentTime + 1, database.PoADifficultyOutOfTurn, false},
+		{"out of turn claiming the in-turn difficulty", 0, parentTime + database.PoAOutOfTurnDelay, database.PoADifficultyInTurn, false},
+		{"out of turn after the delay", 0, parentTime + database.PoAOutOfTurnDelay, database.PoADifficultyOutOfTurn, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := n.state.AddBlock(sealTestBlock(test.sealer, test.time, test.difficulty))
+			if test.valid && err != nil {
+				t.Fatalf("expected the block to be added. %s", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("expected the block to be rejected")
+			}
+		})
+	}
+
+	if n.state.TotalDifficulty() != database.PoADifficultyInTurn+2*database.PoADifficultyOutOfTurn {
+		t.Fatalf("expected the chain to weigh one in-turn and two out-of-turn blocks, total difficulty is %d", n.state.TotalDifficulty())
+	}
+}
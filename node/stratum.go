@@ -0,0 +1,360 @@
+package node
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+const (
+	stratumMethodSubscribe = "mining.subscribe"
+	stratumMethodAuthorize = "mining.authorize"
+	stratumMethodSubmit    = "mining.submit"
+	stratumMethodNotify    = "mining.notify"
+)
+
+const DefaultStratumShareDifficulty = 1
+
+// StratumRequest is a single line-delimited JSON-RPC message exchanged with pool miners.
+// Notifications pushed by the server carry a nil ID.
+type StratumRequest struct {
+	ID     *uint64           `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type StratumResponse struct {
+	ID     *uint64     `json:"id"`
+	Result interface{} `json:"result"`
+	Error  *string     `json:"error"`
+}
+
+// StratumJob is the Work package extended with the lower share target pool miners report against.
+type StratumJob struct {
+	ID              string       `json:"job_id"`
+	Block           PendingBlock `json:"block"`
+	Difficulty      uint         `json:"difficulty"`
+	ShareDifficulty uint         `json:"share_difficulty"`
+}
+
+type StratumSubmitParams struct {
+	Worker string `json:"worker"`
+	JobID  string `json:"job_id"`
+	Nonce  uint32 `json:"nonce"`
+}
+
+type WorkerStats struct {
+	AcceptedShares uint64 `json:"accepted_shares"`
+	RejectedShares uint64 `json:"rejected_shares"`
+	BlocksFound    uint64 `json:"blocks_found"`
+	LastShareTime  uint64 `json:"last_share_time"`
+}
+
+type stratumClient struct {
+	conn       net.Conn
+	encoder    *json.Encoder
+	subscribed bool
+	workers    map[string]bool
+}
+
+type StratumServer struct {
+	node            *Node
+	port            uint64
+	shareDifficulty uint
+
+	mu        sync.Mutex
+	clients   map[*stratumClient]bool
+	workers   map[string]*WorkerStats
+	job       *StratumJob
+	jobShares map[uint32]bool
+}
+
+func NewStratumServer(node *Node, port uint64, shareDifficulty uint) *StratumServer {
+	return &StratumServer{
+		node:            node,
+		port:            port,
+		shareDifficulty: shareDifficulty,
+		clients:         make(map[*stratumClient]bool),
+		workers:         make(map[string]*WorkerStats),
+		jobShares:       make(map[uint32]bool),
+	}
+}
+
+func (s *StratumServer) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Stratum server listening on: %s:%d\n", s.node.info.IP, s.port)
+
+	return s.serve(ctx, listener)
+}
+
+// serve accepts pool miners on the listener until the context is done.
+func (s *StratumServer) serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		err := listener.Close()
+		if err != nil {
+			fmt.Println("Error closing stratum listener:", err)
+		}
+	}()
+
+	go s.watchJobs(ctx)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+
+			return err
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+// WorkerStats returns a snapshot of accepted and rejected shares per worker.
+func (s *StratumServer) WorkerStats() map[string]WorkerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make(map[string]WorkerStats, len(s.workers))
+	for worker, ws := range s.workers {
+		stats[worker] = *ws
+	}
+
+	return stats
+}
+
+// watchJobs pushes a new job to every subscribed client whenever the node signals
+// its work changed, including when a synced block makes the PendingBlock stale.
+func (s *StratumServer) watchJobs(ctx context.Context) {
+	s.refreshJob()
+
+	for {
+		select {
+		case <-s.node.workChanged:
+			s.refreshJob()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refreshJob fetches the node's current work, outside the lock as GetWork may
+// rebuild the PendingBlock, and notifies the subscribed clients when it changed.
+func (s *StratumServer) refreshJob() {
+	work, err := s.node.GetWork()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.job = nil
+		return
+	}
+
+	if s.job != nil && s.job.ID == work.Hash.Hex() {
+		return
+	}
+
+	s.job = &StratumJob{work.Hash.Hex(), work.Block, work.Difficulty, s.shareDifficulty}
+	s.jobShares = make(map[uint32]bool)
+
+	for client := range s.clients {
+		if client.subscribed {
+			s.notify(client, *s.job)
+		}
+	}
+}
+
+func (s *StratumServer) handleConn(conn net.Conn) {
+	client := &stratumClient{conn: conn, encoder: json.NewEncoder(conn), workers: make(map[string]bool)}
+
+	s.mu.Lock()
+	s.clients[client] = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+
+		err := conn.Close()
+		if err != nil {
+			fmt.Println("Error closing stratum connection:", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req StratumRequest
+		err := json.Unmarshal(scanner.Bytes(), &req)
+		if err != nil {
+			s.mu.Lock()
+			s.reply(client, nil, nil, fmt.Errorf("unable to unmarshal request. %s", err.Error()))
+			s.mu.Unlock()
+			continue
+		}
+
+		s.handleRequest(client, req)
+	}
+}
+
+func (s *StratumServer) handleRequest(client *stratumClient, req StratumRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Method {
+	case stratumMethodSubscribe:
+		client.subscribed = true
+		s.reply(client, req.ID, true, nil)
+
+		if s.job != nil {
+			s.notify(client, *s.job)
+		}
+
+	case stratumMethodAuthorize:
+		var worker string
+		if len(req.Params) < 1 || json.Unmarshal(req.Params[0], &worker) != nil || worker == "" {
+			s.reply(client, req.ID, nil, fmt.Errorf("worker name is required"))
+			return
+		}
+
+		client.workers[worker] = true
+		if _, ok := s.workers[worker]; !ok {
+			s.workers[worker] = &WorkerStats{}
+		}
+
+		s.reply(client, req.ID, true, nil)
+
+	case stratumMethodSubmit:
+		params, err := parseStratumSubmitParams(req.Params)
+		if err != nil {
+			s.reply(client, req.ID, nil, err)
+			return
+		}
+
+		if !client.workers[params.Worker] {
+			s.reply(client, req.ID, nil, fmt.Errorf("worker '%s' is not authorized", params.Worker))
+			return
+		}
+
+		err = s.submitShare(params)
+		if err != nil {
+			s.workers[params.Worker].RejectedShares++
+			s.reply(client, req.ID, nil, err)
+			return
+		}
+
+		s.workers[params.Worker].AcceptedShares++
+		s.workers[params.Worker].LastShareTime = uint64(time.Now().Unix())
+		s.reply(client, req.ID, true, nil)
+
+	default:
+		s.reply(client, req.ID, nil, fmt.Errorf("unknown method '%s'", req.Method))
+	}
+}
+
+// submitShare validates a share against the current job and the share difficulty.
+// Shares that also satisfy the network difficulty are submitted to the node as a new block.
+func (s *StratumServer) submitShare(params StratumSubmitParams) error {
+	if s.job == nil || params.JobID != s.job.ID {
+		return fmt.Errorf("stale job '%s'", params.JobID)
+	}
+
+	if s.jobShares[params.Nonce] {
+		return fmt.Errorf("duplicate share with nonce %d", params.Nonce)
+	}
+
+	block := s.job.Block.Block(params.Nonce)
+	hash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+
+	if database.LeadingZeroBytes(hash) < s.shareDifficulty {
+		return fmt.Errorf("share hash %x is above the share target", hash)
+	}
+
+	s.jobShares[params.Nonce] = true
+
+	if database.IsBlockHashValid(hash, s.job.Difficulty) {
+		var workHash database.Hash
+		err = workHash.UnmarshalText([]byte(s.job.ID))
+		if err != nil {
+			return err
+		}
+
+		// the job is spent either way, a new one follows once the node's work changes
+		s.job = nil
+
+		_, err = s.node.SubmitWork(workHash, params.Nonce)
+		if err != nil {
+			return err
+		}
+
+		s.workers[params.Worker].BlocksFound++
+	}
+
+	return nil
+}
+
+func (s *StratumServer) notify(client *stratumClient, job StratumJob) {
+	err := client.encoder.Encode(struct {
+		ID     *uint64      `json:"id"`
+		Method string       `json:"method"`
+		Params []StratumJob `json:"params"`
+	}{nil, stratumMethodNotify, []StratumJob{job}})
+	if err != nil {
+		fmt.Println("Error notifying stratum client:", err)
+	}
+}
+
+func (s *StratumServer) reply(client *stratumClient, id *uint64, result interface{}, err error) {
+	res := StratumResponse{ID: id, Result: result}
+	if err != nil {
+		errMsg := err.Error()
+		res.Error = &errMsg
+	}
+
+	err = client.encoder.Encode(res)
+	if err != nil {
+		fmt.Println("Error replying to stratum client:", err)
+	}
+}
+
+func parseStratumSubmitParams(raw []json.RawMessage) (StratumSubmitParams, error) {
+	params := StratumSubmitParams{}
+
+	if len(raw) != 3 {
+		return params, fmt.Errorf("submit expects [worker, job_id, nonce] params")
+	}
+
+	if err := json.Unmarshal(raw[0], &params.Worker); err != nil {
+		return params, err
+	}
+
+	if err := json.Unmarshal(raw[1], &params.JobID); err != nil {
+		return params, err
+	}
+
+	if err := json.Unmarshal(raw[2], &params.Nonce); err != nil {
+		return params, err
+	}
+
+	return params, nil
+}
@@ -0,0 +1,318 @@
+package node
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
+)
+
+const testMiningDifficulty = 2
+
+// newTestNode sets up a node whose genesis funds a fresh account, without starting it.
+func newTestNode(t *testing.T) (*Node, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc := crypto.PubkeyToAddress(key.PublicKey)
+
+	dataDir := t.TempDir()
+	genesis := fmt.Sprintf(`{"symbol": "ETH", "balances": {"%s": 1000000}}`, acc.Hex())
+	err = database.InitDataDirIfNotExists(dataDir, []byte(genesis))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return loadTestNode(t, dataDir, acc), key
+}
+
+// loadTestNode sets up a node on an existing data dir, without starting it.
+func loadTestNode(t *testing.T, dataDir string, acc common.Address) *Node {
+	t.Helper()
+
+	state, err := database.NewStateFromDisk(dataDir, testMiningDifficulty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		state.Close()
+	})
+
+	n := New(dataDir, DefaultIP, 0, acc, PeerNode{}, testMiningDifficulty)
+	n.state = state
+	pendingState := state.Copy()
+	n.pendingState = &pendingState
+
+	return n
+}
+
+type stratumTestClient struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+	nextID  uint64
+}
+
+func (c *stratumTestClient) call(method string, params ...interface{}) StratumResponse {
+	c.t.Helper()
+
+	c.nextID++
+	id := c.nextID
+
+	rawParams := make([]json.RawMessage, len(params))
+	for i, param := range params {
+		raw, err := json.Marshal(param)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		rawParams[i] = raw
+	}
+
+	reqJson, err := json.Marshal(StratumRequest{ID: &id, Method: method, Params: rawParams})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	_, err = c.conn.Write(append(reqJson, '\n'))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	var res StratumResponse
+	c.read(&res)
+	if res.ID == nil || *res.ID != id {
+		c.t.Fatalf("expected a reply to request %d, got %+v", id, res)
+	}
+
+	return res
+}
+
+func (c *stratumTestClient) read(msg interface{}) {
+	c.t.Helper()
+
+	err := c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if !c.scanner.Scan() {
+		c.t.Fatalf("expected a stratum message. %v", c.scanner.Err())
+	}
+
+	err = json.Unmarshal(c.scanner.Bytes(), msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func TestStratumServer(t *testing.T) {
+	n, key := newTestNode(t)
+
+	tx := database.NewTx(n.info.Account, common.HexToAddress("0x11"), 10, 1, "")
+	signedTx, err := wallet.SignTx(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.AddPendingTX(signedTx, n.info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := NewStratumServer(n, 0, 1)
+	go server.serve(ctx, listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := &stratumTestClient{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+
+	res := client.call(stratumMethodSubscribe)
+	if res.Error != nil || res.Result != true {
+		t.Fatalf("subscribe failed: %+v", res)
+	}
+
+	var notification struct {
+		Method string       `json:"method"`
+		Params []StratumJob `json:"params"`
+	}
+	client.read(&notification)
+	if notification.Method != stratumMethodNotify || len(notification.Params) != 1 {
+		t.Fatalf("expected a job notification, got %+v", notification)
+	}
+
+	job := notification.Params[0]
+	if len(job.Block.TXs) != 1 || job.Difficulty != testMiningDifficulty || job.ShareDifficulty != 1 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	res = client.call(stratumMethodSubmit, "rig", job.ID, 0)
+	if res.Error == nil {
+		t.Fatal("expected a share from an unauthorized worker to be refused")
+	}
+
+	res = client.call(stratumMethodAuthorize, "rig")
+	if res.Error != nil || res.Result != true {
+		t.Fatalf("authorize failed: %+v", res)
+	}
+
+	// find a nonce below the share target only, one above it and one sealing the block
+	var invalidNonce, shareNonce, blockNonce uint32
+	var blockHash database.Hash
+	foundInvalid, foundShare := false, false
+	for nonce := uint32(0); ; nonce++ {
+		hash, err := job.Block.Block(nonce).Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zeroes := database.LeadingZeroBytes(hash)
+		switch {
+		case zeroes == 0 && !foundInvalid:
+			invalidNonce, foundInvalid = nonce, true
+		case zeroes == 1 && !foundShare:
+			shareNonce, foundShare = nonce, true
+		case zeroes >= testMiningDifficulty:
+			blockNonce, blockHash = nonce, hash
+		}
+
+		if blockHash != (database.Hash{}) && foundInvalid && foundShare {
+			break
+		}
+	}
+
+	res = client.call(stratumMethodSubmit, "rig", job.ID, invalidNonce)
+	if res.Error == nil {
+		t.Fatal("expected a share above the share target to be rejected")
+	}
+
+	res = client.call(stratumMethodSubmit, "rig", job.ID, shareNonce)
+	if res.Error != nil || res.Result != true {
+		t.Fatalf("expected the share to be accepted: %+v", res)
+	}
+
+	res = client.call(stratumMethodSubmit, "rig", job.ID, shareNonce)
+	if res.Error == nil {
+		t.Fatal("expected a duplicate share to be rejected")
+	}
+
+	if n.state.LatestBlockHash() == blockHash {
+		t.Fatal("expected a share below the network difficulty not to seal the block")
+	}
+
+	res = client.call(stratumMethodSubmit, "rig", job.ID, blockNonce)
+	if res.Error != nil || res.Result != true {
+		t.Fatalf("expected the block share to be accepted: %+v", res)
+	}
+
+	if n.state.LatestBlockHash() != blockHash {
+		t.Fatalf("expected the submitted block %x to be added, latest block is %x", blockHash, n.state.LatestBlockHash())
+	}
+
+	if n.pendingTXsCount() != 0 {
+		t.Fatalf("expected the mined TX to leave the mempool, %d TXs pending", n.pendingTXsCount())
+	}
+
+	stats := server.WorkerStats()["rig"]
+	if stats.AcceptedShares != 2 || stats.RejectedShares != 2 || stats.BlocksFound != 1 {
+		t.Fatalf("unexpected worker stats %+v", stats)
+	}
+}
+
+func TestStratumServerRejectsBlockRefusedByNode(t *testing.T) {
+	n, key := newTestNode(t)
+
+	tx := database.NewTx(n.info.Account, common.HexToAddress("0x11"), 10, 1, "")
+	signedTx, err := wallet.SignTx(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.AddPendingTX(signedTx, n.info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := NewStratumServer(n, 0, 1)
+	go server.serve(ctx, listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := &stratumTestClient{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+	client.call(stratumMethodSubscribe)
+
+	var notification struct {
+		Params []StratumJob `json:"params"`
+	}
+	client.read(&notification)
+	job := notification.Params[0]
+
+	client.call(stratumMethodAuthorize, "rig")
+
+	// a competing block at the same height makes the node refuse the job's block
+	competingWork := Work{Block: NewPendingBlock(database.Hash{}, 0, common.HexToAddress("0x22"), nil), Difficulty: testMiningDifficulty}
+	competingNonce, _ := findWorkNonce(t, competingWork, true)
+	n.pendingBlockMu.Lock()
+	_, err = n.state.AddBlock(competingWork.Block.Block(competingNonce))
+	n.pendingBlockMu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, _ := findWorkNonce(t, Work{Block: job.Block, Difficulty: job.Difficulty}, true)
+	res := client.call(stratumMethodSubmit, "rig", job.ID, nonce)
+	if res.Error == nil {
+		t.Fatal("expected the block refused by the node to be reported to the miner")
+	}
+
+	// the TX stays pending, so a new job on top of the competing block follows
+	client.read(&notification)
+	if len(notification.Params) != 1 || notification.Params[0].ID == job.ID || notification.Params[0].Block.Number != 1 {
+		t.Fatalf("expected a new job for the next block, got %+v", notification)
+	}
+
+	res = client.call(stratumMethodSubmit, "rig", job.ID, nonce)
+	if res.Error == nil {
+		t.Fatal("expected the job of the refused block to be dropped")
+	}
+
+	stats := server.WorkerStats()["rig"]
+	if stats.BlocksFound != 0 || stats.RejectedShares != 2 {
+		t.Fatalf("unexpected worker stats %+v", stats)
+	}
+}
@@ -108,8 +108,16 @@ func (n *Node) syncBlocks(peer PeerNode, status StatusResponse) error {
 		return err
 	}
 
+	err = n.state.ValidateCheckpoints(blocks)
+	if err != nil {
+		n.alertRefusedFork(peer, err)
+		return err
+	}
+
 	for _, block := range blocks {
+		n.pendingBlockMu.Lock()
 		err = n.addBlock(block)
+		n.pendingBlockMu.Unlock()
 		if err != nil {
 			return err
 		}
@@ -136,13 +144,14 @@ func (n *Node) syncPendingTXs(peer PeerNode, txs []database.SignedTx) error {
 	for _, tx := range txs {
 		err := n.AddPendingTX(tx, peer)
 		if err != nil {
-			return err
+			// one rejected TX mustn't keep the peer's other TXs out
+			fmt.Printf("Rejected TX from Peer %s: %s\n", peer.TcpAddress(), err)
 		}
 	}
 
 	return nil
 }
- 
+
 func (n *Node) joinKnownPeers(peer PeerNode) error {
 	if peer.connected {
 		return nil
@@ -0,0 +1,87 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+// addTimeLockedTX holds a TX that can't be mined yet aside from the pending TXs.
+// Only its signature can be checked now, the rest is validated once it's promoted.
+func (n *Node) addTimeLockedTX(tx database.SignedTx, txHash database.Hash, fromPeer PeerNode) error {
+	ok, err := tx.IsAuthentic()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return txPoolError(TxPoolErrInvalid, "wrong TX. Sender '%s' is forged", tx.From.String())
+	}
+
+	if uint(len(n.timeLockedTXs)) >= n.txPool.config.GlobalSlots {
+		return txPoolError(TxPoolErrPoolFull, "time-locked TXs pool is full with %d TXs", len(n.timeLockedTXs))
+	}
+
+	fmt.Printf("Added Time-locked TX %s from Peer %s, valid after %d\n", txHash.Hex(), fromPeer.TcpAddress(), tx.ValidAfter)
+	n.timeLockedTXs[txHash.Hex()] = tx
+
+	return nil
+}
+
+// updateTimeLockedTXs evicts the expired TXs from the mempool and adds the
+// time-locked TXs that became valid for the next block to the txPool.
+func (n *Node) updateTimeLockedTXs() {
+	number := n.state.NextBlockNumber()
+	now := uint64(time.Now().Unix())
+
+	hasExpired := false
+	for _, tx := range n.txPool.All() {
+		if tx.IsExpired(number, now) {
+			txHash, _ := tx.Hash()
+			fmt.Printf("\t-evicting expired TX: %s\n", txHash.Hex())
+			n.txPool.remove(tx)
+			hasExpired = true
+		}
+	}
+
+	// the sender's later TXs were applied on top of the expired ones
+	if hasExpired {
+		n.revalidateTxPool()
+	}
+
+	ready := make([]database.SignedTx, 0)
+	for txHash, tx := range n.timeLockedTXs {
+		if tx.IsExpired(number, now) {
+			fmt.Printf("\t-evicting expired TX: %s\n", txHash)
+			delete(n.timeLockedTXs, txHash)
+			continue
+		}
+
+		if !tx.IsPremature(number, now) {
+			ready = append(ready, tx)
+			delete(n.timeLockedTXs, txHash)
+		}
+	}
+
+	// promote in nonce order so a sender's TXs unlocked together all apply
+	sort.Slice(ready, func(i, j int) bool {
+		return ready[i].Nonce < ready[j].Nonce
+	})
+
+	for _, tx := range ready {
+		txHash, _ := tx.Hash()
+
+		promoted, err := n.addTxToPool(tx)
+		if err != nil {
+			fmt.Printf("\t-dropping time-locked TX %s: %s\n", txHash.Hex(), err)
+			continue
+		}
+
+		fmt.Printf("\t-promoting time-locked TX: %s\n", txHash.Hex())
+		for _, tx := range promoted {
+			n.notifyPendingTX(tx)
+		}
+	}
+}
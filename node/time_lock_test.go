@@ -0,0 +1,51 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+func TestTimeLockedTXsWaitForTheirBlock(t *testing.T) {
+	n, key := newTestNode(t)
+	recipient := common.HexToAddress("0x11")
+	now := uint64(time.Now().Unix())
+
+	expiring := database.NewTx(n.info.Account, recipient, 10, 1, "")
+	expiring.ValidUntil = now - 1
+	err := n.AddPendingTX(signTestTx(t, expiring, key), n.info)
+	if err == nil {
+		t.Fatal("expected an expired TX to be rejected")
+	}
+
+	// valid from block 1 on
+	locked := database.NewTx(n.info.Account, recipient, 10, 1, "")
+	locked.ValidAfter = 1
+	signedLocked := signTestTx(t, locked, key)
+
+	err = n.AddPendingTX(signedLocked, n.info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(n.timeLockedTXs) != 1 || n.pendingTXsCount() != 0 {
+		t.Fatalf("expected the TX to wait aside from the pending TXs, %d TXs pending", n.pendingTXsCount())
+	}
+
+	premature := NewPendingBlock(database.Hash{}, 0, n.info.Account, []database.SignedTx{signedLocked})
+	_, err = n.state.AddBlock(mineTestBlock(t, premature))
+	if err == nil {
+		t.Fatal("expected a block including the TX before its lock to be rejected")
+	}
+
+	err = n.addBlock(mineTestBlock(t, NewPendingBlock(database.Hash{}, 0, n.info.Account, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(n.timeLockedTXs) != 0 || n.pendingTXsCount() != 1 {
+		t.Fatalf("expected the TX to become pending for block 1, %d TXs pending", n.pendingTXsCount())
+	}
+}
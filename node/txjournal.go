@@ -0,0 +1,173 @@
+package node
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+const txJournalFileName = "txpool.journal"
+
+// txJournal appends the TXs accepted to the mempool to a file in the data dir,
+// one JSON TX per line, so they survive a node restart.
+type txJournal struct {
+	path string
+	f    *os.File
+}
+
+func newTxJournal(dataDir string) *txJournal {
+	return &txJournal{path: filepath.Join(dataDir, txJournalFileName)}
+}
+
+// load reads the journaled TXs, passing each to add. A missing journal is empty.
+func (j *txJournal) load(add func(tx database.SignedTx) error) (loaded int, dropped int, err error) {
+	f, err := os.OpenFile(j.path, os.O_RDONLY, 0o600)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var tx database.SignedTx
+		err = json.Unmarshal(scanner.Bytes(), &tx)
+		if err != nil {
+			return loaded, dropped, fmt.Errorf("corrupted TX journal '%s'. %s", j.path, err.Error())
+		}
+
+		if add(tx) != nil {
+			dropped++
+			continue
+		}
+		loaded++
+	}
+
+	return loaded, dropped, scanner.Err()
+}
+
+func (j *txJournal) insert(tx database.SignedTx) error {
+	if j.f == nil {
+		return fmt.Errorf("TX journal '%s' is not open", j.path)
+	}
+
+	txJson, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = j.f.Write(append(txJson, '\n'))
+
+	return err
+}
+
+// rotate rewrites the journal with only the given TXs, dropping the mined and
+// evicted ones, and reopens it for appending.
+func (j *txJournal) rotate(txs []database.SignedTx) error {
+	tmpPath := j.path + ".new"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		txJson, err := json.Marshal(tx)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+
+		_, err = tmp.Write(append(txJson, '\n'))
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = j.close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, j.path)
+	if err != nil {
+		return err
+	}
+
+	j.f, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o600)
+
+	return err
+}
+
+func (j *txJournal) close() error {
+	if j.f == nil {
+		return nil
+	}
+
+	err := j.f.Close()
+	j.f = nil
+
+	return err
+}
+
+// loadTxJournal re-validates the journaled TXs against the current state, adding
+// the still valid ones back to the mempool, and compacts the journal.
+func (n *Node) loadTxJournal() error {
+	journal := newTxJournal(n.dataDir)
+
+	loaded, dropped, err := journal.load(func(tx database.SignedTx) error {
+		return n.AddPendingTX(tx, n.info)
+	})
+	if err != nil {
+		return err
+	}
+
+	n.txPoolMu.Lock()
+	n.txJournal = journal
+	n.txPoolMu.Unlock()
+
+	if loaded > 0 || dropped > 0 {
+		fmt.Printf("Loaded %d journaled TXs, dropped %d no longer valid\n", loaded, dropped)
+	}
+
+	return n.rotateTxJournal()
+}
+
+// rotateTxJournal rewrites the journal with the TXs currently in the mempool.
+// Holding the mempool lock, no TX is journaled while the file is swapped.
+func (n *Node) rotateTxJournal() error {
+	n.txPoolMu.Lock()
+	defer n.txPoolMu.Unlock()
+
+	txs := n.txPool.All()
+	for _, tx := range n.timeLockedTXs {
+		txs = append(txs, tx)
+	}
+
+	return n.txJournal.rotate(txs)
+}
+
+func (n *Node) journalTX(tx database.SignedTx) {
+	if n.txJournal == nil {
+		return
+	}
+
+	err := n.txJournal.insert(tx)
+	if err != nil {
+		fmt.Printf("ERROR: journaling TX: %s\n", err)
+	}
+}
@@ -0,0 +1,72 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+func TestTxJournalReloadsTXsAfterRestart(t *testing.T) {
+	n, key := newTestNode(t)
+
+	err := n.loadTxJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rotate the journal while TXs are journaled
+	rotated := make(chan error)
+	go func() {
+		for i := 0; i < 20; i++ {
+			err := n.rotateTxJournal()
+			if err != nil {
+				rotated <- err
+				return
+			}
+		}
+		rotated <- nil
+	}()
+
+	// nonce 21 is missing, so the last TX is queued
+	for _, nonce := range []uint{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 22} {
+		tx := signTestTx(t, database.NewTx(n.info.Account, common.HexToAddress("0x11"), 1, nonce, ""), key)
+
+		err = n.AddPendingTX(tx, n.info)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = <-rotated
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.txPoolMu.Lock()
+	err = n.txJournal.close()
+	n.txPoolMu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.state.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := loadTestNode(t, n.dataDir, n.info.Account)
+	err = restarted.loadTxJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.txJournal.close()
+
+	if restarted.txPool.PendingCount() != 20 || restarted.txPool.QueuedCount() != 1 {
+		t.Fatalf("expected the journaled TXs to be reloaded, %d pending and %d queued", restarted.txPool.PendingCount(), restarted.txPool.QueuedCount())
+	}
+
+	if restarted.NextPendingNonce(n.info.Account) != 21 {
+		t.Fatalf("expected the next pending nonce to follow the reloaded TXs, got %d", restarted.NextPendingNonce(n.info.Account))
+	}
+}
@@ -0,0 +1,504 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+const (
+	DefaultTxPoolGlobalSlots  = 4096
+	DefaultTxPoolAccountSlots = 64
+	DefaultTxPoolLifetime     = 3 * time.Hour
+	DefaultTxPoolPriceBump    = 10
+)
+
+// Codes of the reasons a TX is rejected by the txpool.
+const (
+	TxPoolErrInvalid      = "invalid"
+	TxPoolErrKnown        = "already_known"
+	TxPoolErrNonceTooLow  = "nonce_too_low"
+	TxPoolErrNonceTooHigh = "nonce_too_high"
+	TxPoolErrReplacement  = "replacement_underpriced"
+	TxPoolErrSenderLimit  = "sender_limit"
+	TxPoolErrPoolFull     = "pool_full"
+	TxPoolErrUnderpriced  = "underpriced"
+)
+
+// TxPoolError is returned when the txpool rejects a TX, its Code telling why.
+type TxPoolError struct {
+	Code string
+	Err  error
+}
+
+func (e *TxPoolError) Error() string {
+	return e.Err.Error()
+}
+
+func txPoolError(code string, format string, a ...interface{}) *TxPoolError {
+	return &TxPoolError{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// TxPoolConfig bounds the txpool so spamming TXs can't exhaust the node's memory.
+type TxPoolConfig struct {
+	// Max TXs in the pool, pending and queued
+	GlobalSlots uint
+	// Max TXs of a single sender in the pool
+	AccountSlots uint
+	// How long a TX may wait in the pool before it's evicted
+	Lifetime time.Duration
+	// Percentage a TX must raise the fee of the pooled TX with the same nonce by to replace it
+	PriceBump uint
+}
+
+func DefaultTxPoolConfig() TxPoolConfig {
+	return TxPoolConfig{
+		GlobalSlots:  DefaultTxPoolGlobalSlots,
+		AccountSlots: DefaultTxPoolAccountSlots,
+		Lifetime:     DefaultTxPoolLifetime,
+		PriceBump:    DefaultTxPoolPriceBump,
+	}
+}
+
+// TxPool holds the TXs waiting to be mined per sender and nonce. Pending TXs are
+// executable: they follow their sender's nonce without a gap and were applied in
+// order to the pending state. Queued TXs wait for the missing nonces to arrive.
+type TxPool struct {
+	config  TxPoolConfig
+	pending map[common.Address]map[uint]database.SignedTx
+	queued  map[common.Address]map[uint]database.SignedTx
+	byHash  map[string]database.SignedTx
+	// When each TX entered the pool, to evict it once it outlives the config Lifetime
+	addedAt map[string]time.Time
+}
+
+func NewTxPool(config TxPoolConfig) *TxPool {
+	return &TxPool{
+		config:  config,
+		pending: make(map[common.Address]map[uint]database.SignedTx),
+		queued:  make(map[common.Address]map[uint]database.SignedTx),
+		byHash:  make(map[string]database.SignedTx),
+		addedAt: make(map[string]time.Time),
+	}
+}
+
+// emptied returns an empty pool with the same config that remembers when the
+// TXs of this one were added, so re-adding them keeps their age.
+func (p *TxPool) emptied() *TxPool {
+	empty := NewTxPool(p.config)
+	empty.addedAt = p.addedAt
+
+	return empty
+}
+
+func (p *TxPool) Count() int {
+	return len(p.byHash)
+}
+
+func (p *TxPool) Get(txHash string) (database.SignedTx, bool) {
+	tx, ok := p.byHash[txHash]
+
+	return tx, ok
+}
+
+func (p *TxPool) PendingCount() int {
+	count := 0
+	for _, txs := range p.pending {
+		count += len(txs)
+	}
+
+	return count
+}
+
+func (p *TxPool) QueuedCount() int {
+	return len(p.byHash) - p.PendingCount()
+}
+
+// Pending returns the executable TXs, each sender's in nonce order.
+func (p *TxPool) Pending() []database.SignedTx {
+	return flattenSenderTXs(p.PendingBySender())
+}
+
+func (p *TxPool) PendingBySender() map[common.Address][]database.SignedTx {
+	return sortSenderTXs(p.pending)
+}
+
+func (p *TxPool) QueuedBySender() map[common.Address][]database.SignedTx {
+	return sortSenderTXs(p.queued)
+}
+
+// All returns every pooled TX, pending and queued, each sender's in nonce order.
+func (p *TxPool) All() []database.SignedTx {
+	txs := p.Pending()
+	txs = append(txs, flattenSenderTXs(p.QueuedBySender())...)
+
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].Nonce < txs[j].Nonce
+	})
+
+	return txs
+}
+
+func (p *TxPool) addPending(tx database.SignedTx) {
+	p.add(p.pending, tx)
+}
+
+func (p *TxPool) addQueued(tx database.SignedTx) {
+	p.add(p.queued, tx)
+}
+
+func (p *TxPool) add(section map[common.Address]map[uint]database.SignedTx, tx database.SignedTx) {
+	if _, ok := section[tx.From]; !ok {
+		section[tx.From] = make(map[uint]database.SignedTx)
+	}
+	section[tx.From][tx.Nonce] = tx
+
+	txHash, _ := tx.Hash()
+	p.byHash[txHash.Hex()] = tx
+	if _, ok := p.addedAt[txHash.Hex()]; !ok {
+		p.addedAt[txHash.Hex()] = time.Now()
+	}
+}
+
+func (p *TxPool) remove(tx database.SignedTx) {
+	for _, section := range []map[common.Address]map[uint]database.SignedTx{p.pending, p.queued} {
+		delete(section[tx.From], tx.Nonce)
+		if len(section[tx.From]) == 0 {
+			delete(section, tx.From)
+		}
+	}
+
+	txHash, _ := tx.Hash()
+	delete(p.byHash, txHash.Hex())
+	delete(p.addedAt, txHash.Hex())
+}
+
+// popQueued takes the sender's queued TX with the nonce out of the queue.
+func (p *TxPool) popQueued(sender common.Address, nonce uint) (database.SignedTx, bool) {
+	tx, ok := p.queued[sender][nonce]
+	if !ok {
+		return database.SignedTx{}, false
+	}
+
+	delete(p.queued[sender], nonce)
+	if len(p.queued[sender]) == 0 {
+		delete(p.queued, sender)
+	}
+
+	txHash, _ := tx.Hash()
+	delete(p.byHash, txHash.Hex())
+
+	return tx, true
+}
+
+// pooledNonce returns the sender's TX with the nonce, pending or queued.
+func (p *TxPool) pooledNonce(sender common.Address, nonce uint) (database.SignedTx, bool) {
+	if tx, ok := p.pending[sender][nonce]; ok {
+		return tx, true
+	}
+
+	tx, ok := p.queued[sender][nonce]
+
+	return tx, ok
+}
+
+func (p *TxPool) senderCount(sender common.Address) uint {
+	return uint(len(p.pending[sender]) + len(p.queued[sender]))
+}
+
+// cheapestTail finds the TX paying the lowest miner tip among each sender's
+// highest nonce TX, the only ones evictable without leaving a nonce gap.
+func (p *TxPool) cheapestTail(baseFee uint) (database.SignedTx, bool) {
+	var cheapest database.SignedTx
+	found := false
+
+	queued := p.QueuedBySender()
+	tails := make([]database.SignedTx, 0, len(queued)+len(p.pending))
+	for _, txs := range queued {
+		tails = append(tails, txs[len(txs)-1])
+	}
+	for sender, txs := range p.PendingBySender() {
+		if _, isQueued := queued[sender]; !isQueued {
+			tails = append(tails, txs[len(txs)-1])
+		}
+	}
+
+	for _, tx := range tails {
+		if !found || tx.MinerTip(baseFee) < cheapest.MinerTip(baseFee) {
+			cheapest, found = tx, true
+		}
+	}
+
+	return cheapest, found
+}
+
+// expired returns the TXs that have waited in the pool longer than the config Lifetime.
+func (p *TxPool) expired(now time.Time) []database.SignedTx {
+	txs := make([]database.SignedTx, 0)
+	for txHash, tx := range p.byHash {
+		if p.config.Lifetime > 0 && now.Sub(p.addedAt[txHash]) > p.config.Lifetime {
+			txs = append(txs, tx)
+		}
+	}
+
+	return txs
+}
+
+// addTxToPool validates the TX against the pending state when it's the sender's
+// next nonce and queues it when earlier nonces are missing. Queued TXs of the
+// sender that become executable are promoted too. When the pool is full, the TX
+// replaces the cheapest evictable one if it pays a higher tip. It returns the
+// TXs that became pending. A TX with the nonce of a pooled one replaces it if
+// it pays enough more, see replaceTX.
+func (n *Node) addTxToPool(tx database.SignedTx) ([]database.SignedTx, error) {
+	pendingNonce := n.pendingState.GetNextAccountNonce(tx.From)
+
+	if tx.Nonce < n.state.GetNextAccountNonce(tx.From) {
+		return nil, txPoolError(TxPoolErrNonceTooLow, "wrong TX. Sender '%s' nonce %d was already used", tx.From.String(), tx.Nonce)
+	}
+
+	if pooled, ok := n.txPool.pooledNonce(tx.From, tx.Nonce); ok {
+		return n.replaceTX(pooled, tx)
+	}
+
+	if tx.Nonce >= pendingNonce+n.txPool.config.AccountSlots {
+		return nil, txPoolError(TxPoolErrNonceTooHigh, "wrong TX. Sender '%s' nonce %d is too far ahead of its next nonce %d", tx.From.String(), tx.Nonce, pendingNonce)
+	}
+
+	if n.txPool.senderCount(tx.From) >= n.txPool.config.AccountSlots {
+		return nil, txPoolError(TxPoolErrSenderLimit, "wrong TX. Sender '%s' already has %d pooled TXs", tx.From.String(), n.txPool.config.AccountSlots)
+	}
+
+	var evict *database.SignedTx
+	if uint(n.txPool.Count()) >= n.txPool.config.GlobalSlots {
+		baseFee := n.state.NextBaseFee()
+
+		cheapest, ok := n.txPool.cheapestTail(baseFee)
+		if !ok {
+			return nil, txPoolError(TxPoolErrPoolFull, "txpool is full with %d TXs", n.txPool.Count())
+		}
+
+		if tx.MinerTip(baseFee) <= cheapest.MinerTip(baseFee) {
+			return nil, txPoolError(TxPoolErrUnderpriced, "wrong TX. txpool is full and the TX tip %d doesn't beat the lowest pooled tip %d", tx.MinerTip(baseFee), cheapest.MinerTip(baseFee))
+		}
+		evict = &cheapest
+	}
+
+	promoted := make([]database.SignedTx, 0)
+
+	if tx.Nonce > pendingNonce {
+		ok, err := tx.IsAuthentic()
+		if err != nil {
+			return nil, txPoolError(TxPoolErrInvalid, "%s", err.Error())
+		}
+
+		if !ok {
+			return nil, txPoolError(TxPoolErrInvalid, "wrong TX. Sender '%s' is forged", tx.From.String())
+		}
+
+		n.txPool.addQueued(tx)
+	} else {
+		err := n.validateTxBeforeAddingToMempool(tx)
+		if err != nil {
+			return nil, txPoolError(TxPoolErrInvalid, "%s", err.Error())
+		}
+		n.txPool.addPending(tx)
+
+		promoted = append([]database.SignedTx{tx}, n.promoteQueuedTXs(tx.From)...)
+	}
+
+	if evict != nil {
+		n.evictTX(*evict, "underpriced")
+	}
+
+	return promoted, nil
+}
+
+// replaceTX swaps the pooled TX for one with the same sender and nonce, as long
+// as both its fee cap and miner tip are raised by at least the config PriceBump
+// percentage. The replacement is validated before the pooled TX is touched, so
+// an invalid one leaves the pool as it was.
+func (n *Node) replaceTX(pooled database.SignedTx, tx database.SignedTx) ([]database.SignedTx, error) {
+	baseFee := n.state.NextBaseFee()
+	bump := n.txPool.config.PriceBump
+
+	if !isFeeBumped(pooled.FeeCap(), tx.FeeCap(), bump) || tx.FeeCap() <= pooled.FeeCap() ||
+		!isFeeBumped(pooled.MinerTip(baseFee), tx.MinerTip(baseFee), bump) {
+		return nil, txPoolError(TxPoolErrReplacement, "wrong TX. Replacing sender '%s' TX with nonce %d requires a %d%% higher fee cap and tip than %d and %d", tx.From.String(), tx.Nonce, bump, pooled.FeeCap(), pooled.MinerTip(baseFee))
+	}
+
+	err := n.validateReplacement(pooled, tx)
+	if err != nil {
+		return nil, txPoolError(TxPoolErrInvalid, "%s", err.Error())
+	}
+
+	pooledHash, _ := pooled.Hash()
+	pooledAddedAt := n.txPool.addedAt[pooledHash.Hex()]
+
+	_, isPending := n.txPool.pending[pooled.From][pooled.Nonce]
+	n.txPool.remove(pooled)
+
+	// the pooled TX was applied to the pending state, which is rebuilt without it
+	if isPending {
+		n.revalidateTxPool()
+	}
+
+	promoted, err := n.addTxToPool(tx)
+	if err != nil {
+		// restore the pooled TX with its original age
+		n.txPool.addedAt[pooledHash.Hex()] = pooledAddedAt
+		_, restoreErr := n.addTxToPool(pooled)
+		if restoreErr != nil {
+			delete(n.txPool.addedAt, pooledHash.Hex())
+			return nil, fmt.Errorf("%w. The replaced TX %s was dropped too: %s", err, pooledHash.Hex(), restoreErr)
+		}
+
+		return nil, err
+	}
+
+	txHash, _ := tx.Hash()
+	fmt.Printf("\t-replaced TX %s with %s\n", pooledHash.Hex(), txHash.Hex())
+
+	return promoted, nil
+}
+
+// validateReplacement checks the replacement of a pending TX against the pending
+// state it will be applied to: the pending TXs without the replaced one and the
+// sender's later ones. A queued TX is only required to be authentic, like any
+// TX waiting for earlier nonces.
+func (n *Node) validateReplacement(pooled database.SignedTx, tx database.SignedTx) error {
+	ok, err := tx.IsAuthentic()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("wrong TX. Sender '%s' is forged", tx.From.String())
+	}
+
+	if _, isPending := n.txPool.pending[pooled.From][pooled.Nonce]; !isPending {
+		return nil
+	}
+
+	pendingState := n.state.Copy()
+	for _, pending := range n.txPool.Pending() {
+		if pending.From == tx.From && pending.Nonce >= tx.Nonce {
+			continue
+		}
+
+		// a TX failing without the replaced one is dropped by the rebuild as well
+		_ = database.ApplyTx(pending, &pendingState)
+	}
+
+	return database.ApplyTx(tx, &pendingState)
+}
+
+func isFeeBumped(fee, newFee, bumpPercent uint) bool {
+	return newFee*100 >= fee*(100+bumpPercent)
+}
+
+// evictTX drops the TX from the pool. A pending TX was applied to the pending
+// state, so the pool is re-validated without it.
+func (n *Node) evictTX(tx database.SignedTx, reason string) {
+	txHash, _ := tx.Hash()
+	fmt.Printf("\t-evicting %s TX: %s\n", reason, txHash.Hex())
+
+	_, isPending := n.txPool.pending[tx.From][tx.Nonce]
+	n.txPool.remove(tx)
+
+	if isPending {
+		n.revalidateTxPool()
+	}
+}
+
+// evictStaleTXs drops the TXs that waited in the pool longer than its Lifetime.
+func (n *Node) evictStaleTXs() {
+	stale := n.txPool.expired(time.Now())
+	for _, tx := range stale {
+		txHash, _ := tx.Hash()
+		fmt.Printf("\t-evicting stale TX: %s\n", txHash.Hex())
+		n.txPool.remove(tx)
+	}
+
+	if len(stale) > 0 {
+		n.revalidateTxPool()
+	}
+}
+
+// promoteQueuedTXs moves the sender's queued TXs that follow its pending nonce
+// to the pending TXs, dropping the first one that turns out to be invalid.
+func (n *Node) promoteQueuedTXs(sender common.Address) []database.SignedTx {
+	promoted := make([]database.SignedTx, 0)
+
+	for {
+		tx, ok := n.txPool.popQueued(sender, n.pendingState.GetNextAccountNonce(sender))
+		if !ok {
+			return promoted
+		}
+
+		txHash, _ := tx.Hash()
+
+		err := n.validateTxBeforeAddingToMempool(tx)
+		if err != nil {
+			fmt.Printf("\t-dropping queued TX %s: %s\n", txHash.Hex(), err)
+			delete(n.txPool.addedAt, txHash.Hex())
+			return promoted
+		}
+
+		fmt.Printf("\t-promoting queued TX: %s\n", txHash.Hex())
+		n.txPool.addPending(tx)
+		promoted = append(promoted, tx)
+	}
+}
+
+// revalidateTxPool rebuilds the pending state on top of the latest block and
+// re-adds every pooled TX to it, dropping the mined, stale and no longer valid ones.
+func (n *Node) revalidateTxPool() {
+	pendingState := n.state.Copy()
+	n.pendingState = &pendingState
+
+	txs := n.txPool.All()
+	n.txPool = n.txPool.emptied()
+
+	for _, tx := range txs {
+		txHash, _ := tx.Hash()
+
+		if tx.Nonce < n.state.GetNextAccountNonce(tx.From) {
+			delete(n.txPool.addedAt, txHash.Hex())
+			continue
+		}
+
+		_, err := n.addTxToPool(tx)
+		if err != nil {
+			fmt.Printf("\t-dropping pooled TX %s: %s\n", txHash.Hex(), err)
+			delete(n.txPool.addedAt, txHash.Hex())
+		}
+	}
+}
+
+func sortSenderTXs(section map[common.Address]map[uint]database.SignedTx) map[common.Address][]database.SignedTx {
+	senderTXs := make(map[common.Address][]database.SignedTx)
+	for sender, txs := range section {
+		for _, tx := range txs {
+			senderTXs[sender] = append(senderTXs[sender], tx)
+		}
+
+		sort.Slice(senderTXs[sender], func(i, j int) bool {
+			return senderTXs[sender][i].Nonce < senderTXs[sender][j].Nonce
+		})
+	}
+
+	return senderTXs
+}
+
+func flattenSenderTXs(senderTXs map[common.Address][]database.SignedTx) []database.SignedTx {
+	txs := make([]database.SignedTx, 0)
+	for _, sTXs := range senderTXs {
+		txs = append(txs, sTXs...)
+	}
+
+	return txs
+}
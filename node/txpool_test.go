@@ -0,0 +1,248 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
+)
+
+func signTestTx(t *testing.T, tx database.Tx, key *ecdsa.PrivateKey) database.SignedTx {
+	t.Helper()
+
+	signedTx, err := wallet.SignTx(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signedTx
+}
+
+func TestReplaceTX(t *testing.T) {
+	n, key := newTestNode(t)
+	recipient := common.HexToAddress("0x11")
+
+	pooled := signTestTx(t, database.NewTx(n.info.Account, recipient, 10, 1, ""), key)
+	next := signTestTx(t, database.NewTx(n.info.Account, recipient, 10, 2, ""), key)
+	for _, tx := range []database.SignedTx{pooled, next} {
+		err := n.AddPendingTX(tx, n.info)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pooledHash, err := pooled.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addedAt := n.txPool.addedAt[pooledHash.Hex()]
+
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	underpriced := database.NewTx(n.info.Account, recipient, 20, 1, "")
+	underpriced.Fee = database.TxFee + 1
+
+	replacement := database.NewTx(n.info.Account, recipient, 20, 1, "")
+	replacement.Fee = 2 * database.TxFee
+
+	overspending := replacement
+	overspending.Value = 1000000
+
+	tests := []struct {
+		name string
+		tx   database.SignedTx
+		code string
+	}{
+		{"fee not bumped enough", signTestTx(t, underpriced, key), TxPoolErrReplacement},
+		{"forged sender", signTestTx(t, replacement, otherKey), TxPoolErrInvalid},
+		{"spending more than the balance", signTestTx(t, overspending, key), TxPoolErrInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := n.addTxToPool(test.tx)
+
+			var txPoolErr *TxPoolError
+			if !errors.As(err, &txPoolErr) || txPoolErr.Code != test.code {
+				t.Fatalf("expected the replacement to be rejected as %s, got %v", test.code, err)
+			}
+
+			if _, ok := n.txPool.pending[n.info.Account][1]; !ok || n.txPool.PendingCount() != 2 {
+				t.Fatal("expected the pooled TXs to stay pending")
+			}
+
+			if n.txPool.addedAt[pooledHash.Hex()] != addedAt {
+				t.Fatal("expected the pooled TX to keep its age")
+			}
+		})
+	}
+
+	signedReplacement := signTestTx(t, replacement, key)
+	_, err = n.addTxToPool(signedReplacement)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replacementHash, err := signedReplacement.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := n.txPool.Get(replacementHash.Hex()); !ok || n.txPool.PendingCount() != 2 {
+		t.Fatalf("expected the replacement and the sender's next TX to be pending, %d TXs pending", n.txPool.PendingCount())
+	}
+
+	if _, ok := n.txPool.Get(pooledHash.Hex()); ok {
+		t.Fatal("expected the replaced TX to leave the pool")
+	}
+}
+
+func TestQueuedTXsWaitForTheMissingNonces(t *testing.T) {
+	n, key := newTestNode(t)
+	recipient := common.HexToAddress("0x11")
+
+	txs := make([]database.SignedTx, 0, 3)
+	for nonce := uint(1); nonce <= 3; nonce++ {
+		txs = append(txs, signTestTx(t, database.NewTx(n.info.Account, recipient, 10, nonce, ""), key))
+	}
+
+	for _, tx := range []database.SignedTx{txs[2], txs[1]} {
+		err := n.AddPendingTX(tx, n.info)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n.txPool.QueuedCount() != 2 || n.txPool.PendingCount() != 0 {
+		t.Fatalf("expected the TXs after the nonce gap to be queued, %d TXs queued", n.txPool.QueuedCount())
+	}
+
+	err := n.AddPendingTX(txs[0], n.info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.txPool.QueuedCount() != 0 || n.txPool.PendingCount() != 3 {
+		t.Fatalf("expected filling the gap to promote the queued TXs, %d TXs pending", n.txPool.PendingCount())
+	}
+
+	err = n.addBlock(mineTestBlock(t, NewPendingBlock(database.Hash{}, 0, n.info.Account, txs[:1])))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.txPool.PendingCount() != 2 {
+		t.Fatalf("expected the mined TX to leave the pool, %d TXs pending", n.txPool.PendingCount())
+	}
+
+	reused := database.NewTx(n.info.Account, recipient, 20, 1, "")
+	reused.Fee = 2 * database.TxFee
+
+	tests := []struct {
+		name string
+		tx   database.SignedTx
+		code string
+	}{
+		{"mined nonce", signTestTx(t, reused, key), TxPoolErrNonceTooLow},
+		{"nonce too far ahead", signTestTx(t, database.NewTx(n.info.Account, recipient, 10, 4+DefaultTxPoolAccountSlots, ""), key), TxPoolErrNonceTooHigh},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := n.addTxToPool(test.tx)
+
+			var txPoolErr *TxPoolError
+			if !errors.As(err, &txPoolErr) || txPoolErr.Code != test.code {
+				t.Fatalf("expected the TX to be rejected as %s, got %v", test.code, err)
+			}
+		})
+	}
+}
+
+func TestTxPoolLimits(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	balances := make([]string, len(keys))
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+		balances[i] = fmt.Sprintf(`"%s": 1000000`, crypto.PubkeyToAddress(key.PublicKey).Hex())
+	}
+
+	dataDir := t.TempDir()
+	genesis := fmt.Sprintf(`{"symbol": "ETH", "balances": {%s}}`, strings.Join(balances, ", "))
+	err := database.InitDataDirIfNotExists(dataDir, []byte(genesis))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := loadTestNode(t, dataDir, common.HexToAddress("0x22"))
+	n.SetTxPoolConfig(TxPoolConfig{GlobalSlots: 2, AccountSlots: 2, Lifetime: time.Minute})
+	recipient := common.HexToAddress("0x11")
+
+	tx := func(key *ecdsa.PrivateKey, nonce uint, fee uint) database.SignedTx {
+		tx := database.NewTx(crypto.PubkeyToAddress(key.PublicKey), recipient, 10, nonce, "")
+		tx.Fee = fee
+
+		return signTestTx(t, tx, key)
+	}
+
+	cheapest := tx(keys[0], 1, database.TxFee)
+	for _, tx := range []database.SignedTx{cheapest, tx(keys[1], 1, 3*database.TxFee)} {
+		_, err := n.addTxToPool(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = n.addTxToPool(tx(keys[1], 2, database.TxFee))
+
+	var txPoolErr *TxPoolError
+	if !errors.As(err, &txPoolErr) || txPoolErr.Code != TxPoolErrUnderpriced {
+		t.Fatalf("expected a TX not outbidding the cheapest one in the full pool to be rejected, got %v", err)
+	}
+
+	outbidding := tx(keys[1], 2, 2*database.TxFee)
+	_, err = n.addTxToPool(outbidding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cheapestHash, err := cheapest.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := n.txPool.Get(cheapestHash.Hex()); ok || n.txPool.Count() != 2 {
+		t.Fatalf("expected the TX paying the lowest tip to be evicted, %d TXs pooled", n.txPool.Count())
+	}
+
+	_, err = n.addTxToPool(tx(keys[1], 3, 4*database.TxFee))
+	if !errors.As(err, &txPoolErr) || txPoolErr.Code != TxPoolErrSenderLimit {
+		t.Fatalf("expected a TX above the sender's slots to be rejected, got %v", err)
+	}
+
+	outbiddingHash, err := outbidding.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.txPool.addedAt[outbiddingHash.Hex()] = time.Now().Add(-2 * time.Minute)
+
+	n.evictStaleTXs()
+
+	if _, ok := n.txPool.Get(outbiddingHash.Hex()); ok || n.txPool.Count() != 1 {
+		t.Fatalf("expected the TX outliving the pool lifetime to be evicted, %d TXs pooled", n.txPool.Count())
+	}
+}
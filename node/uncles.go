@@ -0,0 +1,64 @@
+package node
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+// rememberUncleCandidates keeps the headers of the blocks about to be removed by a
+// reorg so the work of their miners can still be rewarded by including them as uncles.
+func (n *Node) rememberUncleCandidates(forkedBlock database.Block) error {
+	// an empty forked block means the chains differ since the genesis block
+	forkedBlockHash := database.Hash{}
+	if !reflect.DeepEqual(forkedBlock, database.Block{}) {
+		hash, err := forkedBlock.Hash()
+		if err != nil {
+			return err
+		}
+		forkedBlockHash = hash
+	}
+
+	blocks, err := database.GetBlocksAfter(forkedBlockHash, n.dataDir)
+	if err != nil {
+		return err
+	}
+
+	for _, block := range blocks {
+		blockHash, err := block.Hash()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Keeping forked Block '%s' as uncle candidate\n", blockHash.Hex())
+		n.uncleCandidates[blockHash] = block.Header
+	}
+
+	return nil
+}
+
+// selectUncles picks up to MaxUncles valid uncle candidates for the block at the
+// given height, forgetting the ones too old to ever be included.
+func (n *Node) selectUncles(number uint64) []database.BlockHeader {
+	uncles := make([]database.BlockHeader, 0)
+
+	for hash, candidate := range n.uncleCandidates {
+		if candidate.Number+database.MaxUncleDepth < number {
+			delete(n.uncleCandidates, hash)
+			continue
+		}
+
+		if len(uncles) == database.MaxUncles {
+			continue
+		}
+
+		if n.state.ValidateUncle(candidate, number) != nil {
+			continue
+		}
+
+		uncles = append(uncles, candidate)
+	}
+
+	return uncles
+}
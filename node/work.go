@@ -0,0 +1,91 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+)
+
+// Work is the package handed to external miners. It carries every header field
+// and TX of the PendingBlock so the miner can rebuild the exact block, plus the
+// target the block hash has to satisfy.
+type Work struct {
+	Hash       database.Hash `json:"work_hash"`
+	Block      PendingBlock  `json:"block"`
+	Difficulty uint          `json:"difficulty"`
+	Target     database.Hash `json:"target"`
+}
+
+// GetWork returns the current PendingBlock as a Work package, preparing a new one
+// from the pending TXs whenever the previous one is missing or stale.
+func (n *Node) GetWork() (Work, error) {
+	n.pendingBlockMu.Lock()
+	defer n.pendingBlockMu.Unlock()
+
+	if n.state.Consensus().Name() != database.ConsensusPoW {
+		return Work{}, fmt.Errorf("external mining requires PoW consensus")
+	}
+
+	if len(n.pendingBlock.TXs) == 0 || n.pendingBlock.Parent != n.state.LatestBlockHash() || n.pendingBlock.Number != n.state.NextBlockNumber() {
+		if n.pendingTXsCount() == 0 {
+			return Work{}, fmt.Errorf("no pending TXs to mine")
+		}
+
+		n.pendingBlock = n.newPendingBlock()
+		if len(n.pendingBlock.TXs) == 0 {
+			return Work{}, fmt.Errorf("no pending TXs fit in a block")
+		}
+	}
+
+	workHash, err := n.pendingBlock.Hash()
+	if err != nil {
+		return Work{}, err
+	}
+
+	return Work{workHash, n.pendingBlock, n.miningDifficulty, database.BlockHashTarget(n.miningDifficulty)}, nil
+}
+
+// SubmitWork seals the PendingBlock identified by workHash with the given nonce,
+// validates the resulting hash against the mining difficulty and adds the block.
+func (n *Node) SubmitWork(workHash database.Hash, nonce uint32) (database.Hash, error) {
+	n.pendingBlockMu.Lock()
+	defer n.pendingBlockMu.Unlock()
+
+	if len(n.pendingBlock.TXs) == 0 {
+		return database.Hash{}, fmt.Errorf("no work in progress")
+	}
+
+	currentWorkHash, err := n.pendingBlock.Hash()
+	if err != nil {
+		return database.Hash{}, err
+	}
+
+	if workHash != currentWorkHash {
+		return database.Hash{}, fmt.Errorf("stale work '%s', current work is '%s'", workHash.Hex(), currentWorkHash.Hex())
+	}
+
+	block := n.pendingBlock.Block(nonce)
+
+	blockHash, err := block.Hash()
+	if err != nil {
+		return database.Hash{}, err
+	}
+
+	if !database.IsBlockHashValid(blockHash, n.miningDifficulty) {
+		return database.Hash{}, fmt.Errorf("invalid block hash %x for nonce %d", blockHash, nonce)
+	}
+
+	fmt.Printf("\nExternal miner sealed Block '%x' 🎉\n", blockHash)
+
+	n.pendingBlock = PendingBlock{}
+	n.notifyWorkChanged()
+
+	err = n.addBlock(block)
+	if err != nil {
+		return database.Hash{}, err
+	}
+
+	n.removeMinedPendingTXs(block)
+
+	return blockHash, nil
+}
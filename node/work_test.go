@@ -0,0 +1,144 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ngoduongkha/go-ethereum-cloner/database"
+	"github.com/ngoduongkha/go-ethereum-cloner/wallet"
+)
+
+// findWorkNonce returns the first nonce sealing the work, or one that doesn't when valid is false.
+func findWorkNonce(t *testing.T, work Work, valid bool) (uint32, database.Hash) {
+	t.Helper()
+
+	for nonce := uint32(0); ; nonce++ {
+		hash, err := work.Block.Block(nonce).Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if database.IsBlockHashValid(hash, work.Difficulty) == valid {
+			return nonce, hash
+		}
+	}
+}
+
+// mineTestBlock seals the PendingBlock at the test mining difficulty.
+func mineTestBlock(t *testing.T, pb PendingBlock) database.Block {
+	t.Helper()
+
+	nonce, _ := findWorkNonce(t, Work{Block: pb, Difficulty: testMiningDifficulty}, true)
+
+	return pb.Block(nonce)
+}
+
+func TestGetWorkAndSubmitWork(t *testing.T) {
+	n, key := newTestNode(t)
+
+	_, err := n.GetWork()
+	if err == nil {
+		t.Fatal("expected no work without pending TXs")
+	}
+
+	tx := database.NewTx(n.info.Account, common.HexToAddress("0x11"), 10, 1, "")
+	signedTx, err := wallet.SignTx(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.AddPendingTX(signedTx, n.info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := n.GetWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(work.Block.TXs) != 1 || work.Difficulty != testMiningDifficulty || work.Target != database.BlockHashTarget(testMiningDifficulty) {
+		t.Fatalf("unexpected work %+v", work)
+	}
+
+	again, err := n.GetWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Hash != work.Hash {
+		t.Fatal("expected the same work while the pending block is current")
+	}
+
+	_, err = n.SubmitWork(database.Hash{}, 0)
+	if err == nil {
+		t.Fatal("expected stale work to be rejected")
+	}
+
+	invalidNonce, _ := findWorkNonce(t, work, false)
+	_, err = n.SubmitWork(work.Hash, invalidNonce)
+	if err == nil {
+		t.Fatal("expected a nonce not sealing the block to be rejected")
+	}
+
+	nonce, blockHash := findWorkNonce(t, work, true)
+	addedHash, err := n.SubmitWork(work.Hash, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addedHash != blockHash || n.state.LatestBlockHash() != blockHash {
+		t.Fatalf("expected block %x to be added, latest block is %x", blockHash, n.state.LatestBlockHash())
+	}
+
+	txHash, err := signedTx.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, archived := n.archivedTXs[txHash.Hex()]; n.txPool.PendingCount() != 0 || !archived {
+		t.Fatalf("expected the mined TX %s to be archived, %d TXs pending", txHash.Hex(), n.txPool.PendingCount())
+	}
+
+	_, err = n.SubmitWork(work.Hash, nonce)
+	if err == nil {
+		t.Fatal("expected submitting the same work twice to be rejected")
+	}
+}
+
+func TestSubmitWorkKeepsTXsOfRejectedBlock(t *testing.T) {
+	n, key := newTestNode(t)
+
+	tx := database.NewTx(n.info.Account, common.HexToAddress("0x11"), 10, 1, "")
+	signedTx, err := wallet.SignTx(tx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.AddPendingTX(signedTx, n.info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := n.GetWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a competing block at the same height makes the sealed work invalid
+	competingWork := Work{Block: NewPendingBlock(database.Hash{}, 0, common.HexToAddress("0x22"), nil), Difficulty: testMiningDifficulty}
+	competingNonce, _ := findWorkNonce(t, competingWork, true)
+	_, err = n.state.AddBlock(competingWork.Block.Block(competingNonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, _ := findWorkNonce(t, work, true)
+	_, err = n.SubmitWork(work.Hash, nonce)
+	if err == nil {
+		t.Fatal("expected the block conflicting with the chain to be rejected")
+	}
+
+	if n.txPool.PendingCount() != 1 {
+		t.Fatalf("expected the TX of the rejected block to stay pending, %d TXs pending", n.txPool.PendingCount())
+	}
+}
@@ -3,6 +3,7 @@ package wallet
 import (
 	"crypto/ecdsa"
 	"crypto/sha256"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -19,8 +20,8 @@ func GetKeystoreDirPath() string {
 	return filepath.Join(keystoreDirName)
 }
 
-func NewKeystoreAccount(password string) (common.Address, error) {
-	ks := keystore.NewKeyStore(GetKeystoreDirPath(), keystore.StandardScryptN, keystore.StandardScryptP)
+func NewKeystoreAccount(password, keystoreDir string) (common.Address, error) {
+	ks := keystore.NewKeyStore(keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
 	acc, err := ks.NewAccount(password)
 	if err != nil {
 		return common.Address{}, err
@@ -30,28 +31,32 @@ func NewKeystoreAccount(password string) (common.Address, error) {
 }
 
 func SignTxWithKeystoreAccount(tx database.Tx, acc common.Address, pwd, keystoreDir string) (database.SignedTx, error) {
-	ks := keystore.NewKeyStore(keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
-	ksAccount, err := ks.Find(accounts.Account{Address: acc})
+	key, err := DecryptKeystoreAccount(acc, pwd, keystoreDir)
 	if err != nil {
 		return database.SignedTx{}, err
 	}
 
-	ksAccountJson, err := os.ReadFile(ksAccount.URL.Path)
+	signedTx, err := SignTx(tx, key.PrivateKey)
 	if err != nil {
 		return database.SignedTx{}, err
 	}
 
-	key, err := keystore.DecryptKey(ksAccountJson, pwd)
+	return signedTx, nil
+}
+
+func DecryptKeystoreAccount(acc common.Address, pwd, keystoreDir string) (*keystore.Key, error) {
+	ks := keystore.NewKeyStore(keystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
+	ksAccount, err := ks.Find(accounts.Account{Address: acc})
 	if err != nil {
-		return database.SignedTx{}, err
+		return nil, err
 	}
 
-	signedTx, err := SignTx(tx, key.PrivateKey)
+	ksAccountJson, err := os.ReadFile(ksAccount.URL.Path)
 	if err != nil {
-		return database.SignedTx{}, err
+		return nil, err
 	}
 
-	return signedTx, nil
+	return keystore.DecryptKey(ksAccountJson, pwd)
 }
 
 func SignTx(tx database.Tx, privKey *ecdsa.PrivateKey) (database.SignedTx, error) {
@@ -68,8 +73,90 @@ func SignTx(tx database.Tx, privKey *ecdsa.PrivateKey) (database.SignedTx, error
 	return database.NewSignedTx(tx, sig), nil
 }
 
+// SignRawTx signs the TX offline and returns its canonical encoding to submit to
+// a node's POST /tx/send-raw, so the private key never leaves the client.
+func SignRawTx(tx database.Tx, privKey *ecdsa.PrivateKey) (string, error) {
+	signedTx, err := SignTx(tx, privKey)
+	if err != nil {
+		return "", err
+	}
+
+	return signedTx.EncodeRaw()
+}
+
+func SignRawTxWithKeystoreAccount(tx database.Tx, acc common.Address, pwd, keystoreDir string) (string, error) {
+	key, err := DecryptKeystoreAccount(acc, pwd, keystoreDir)
+	if err != nil {
+		return "", err
+	}
+
+	return SignRawTx(tx, key.PrivateKey)
+}
+
 func Sign(msg []byte, privKey *ecdsa.PrivateKey) (sig []byte, err error) {
 	msgHash := sha256.Sum256(msg)
 
 	return crypto.Sign(msgHash[:], privKey)
 }
+
+// SealBlock signs the block's SealHash for PoA consensus.
+func SealBlock(b database.Block, privKey *ecdsa.PrivateKey) (database.Block, error) {
+	sealHash, err := b.SealHash()
+	if err != nil {
+		return database.Block{}, err
+	}
+
+	seal, err := crypto.Sign(sealHash[:], privKey)
+	if err != nil {
+		return database.Block{}, err
+	}
+
+	b.Header.Seal = seal
+
+	return b, nil
+}
+
+// NewMultisigTx starts collecting the signatures of a TX sent from the multisig account.
+func NewMultisigTx(tx database.Tx, multisig database.MultisigAccount) (database.SignedTx, error) {
+	if tx.From != multisig.Address() {
+		return database.SignedTx{}, fmt.Errorf("TX sender '%s' is not the multisig account '%s'", tx.From.String(), multisig.Address().String())
+	}
+
+	return database.SignedTx{Tx: tx, Multisig: &multisig}, nil
+}
+
+// SignMultisigTx adds the signer's partial signature to the multisig TX. Signers can
+// sign offline one after another, passing the partially signed TX JSON along.
+func SignMultisigTx(tx database.SignedTx, privKey *ecdsa.PrivateKey) (database.SignedTx, error) {
+	if !tx.IsMultisig() {
+		return database.SignedTx{}, fmt.Errorf("TX is not sent from a multisig account")
+	}
+
+	signer := crypto.PubkeyToAddress(privKey.PublicKey)
+	if !tx.Multisig.IsSigner(signer) {
+		return database.SignedTx{}, fmt.Errorf("'%s' is not a signer of the multisig account '%s'", signer.String(), tx.From.String())
+	}
+
+	rawTx, err := tx.Tx.Encode()
+	if err != nil {
+		return database.SignedTx{}, err
+	}
+
+	sig, err := Sign(rawTx, privKey)
+	if err != nil {
+		return database.SignedTx{}, err
+	}
+
+	tx.Sigs = append(append([][]byte{}, tx.Sigs...), sig)
+
+	return tx, nil
+}
+
+func SignMultisigTxWithKeystoreAccount(tx database.SignedTx, acc common.Address, pwd, keystoreDir string) (database.SignedTx, error) {
+	key, err := DecryptKeystoreAccount(acc, pwd, keystoreDir)
+	if err != nil {
+		return database.SignedTx{}, err
+	}
+
+	return SignMultisigTx(tx, key.PrivateKey)
+}